@@ -1,77 +0,0 @@
-package main
-
-import (
-	"time"
-	"bytes"
-	"encoding/gob"
-	"log"
-	"crypto/sha256"
-	"io"
-)
-
-// Represents a block in the blockchain
-//
-// Timestamp: the timestamp for when the block was created
-// Data: actual information contained in the block
-// PrevBlockHash: the hash of the previous block
-// Hash: the hash of the current block
-type Block struct {
-	Timestamp     int64
-	Transactions  []*Transaction
-	PrevBlockHash []byte
-	Hash          []byte
-	Nonce         int
-}
-
-func (b *Block) HashTransactions() []byte {
-	var txHashes [][]byte
-	var txHash [32]byte
-
-	for _, tx := range b.Transactions {
-		txHashes = append(txHashes, tx.Hash())
-	}
-	txHash = sha256.Sum256(bytes.Join(txHashes, []byte{}))
-
-	return txHash[:]
-}
-
-func (b *Block) Serialize() []byte {
-	var result bytes.Buffer
-	encoder := gob.NewEncoder(&result)
-	err := encoder.Encode(b)
-	if err != nil {
-		log.Panic(err)
-	}
-	return result.Bytes()
-}
-
-// Create a new block, populate the fields and return it to the calling method
-func NewBlock(transactions []*Transaction, prevBlockHash []byte) *Block {
-	block := &Block{time.Now().Unix(), transactions, prevBlockHash, []byte{}, 0}
-	pow := NewProofOfWork(block)
-	nonce, hash := pow.Run()
-
-	block.Hash = hash[:]
-	block.Nonce = nonce
-
-	return block
-}
-
-// A function for generating a Genesis block, needed as the first block in a
-// blockchain
-func NewGenesisBlock(coinbase *Transaction) *Block {
-	return NewBlock([]*Transaction{coinbase}, []byte{})
-}
-
-// Take the byte array, decode the block and return the struct
-func DeserializeBlock(d []byte) *Block {
-	var block Block
-	decoder := gob.NewDecoder(bytes.NewReader(d))
-	err := decoder.Decode(&block)
-
-	if err != nil && err != io.EOF {
-		log.Fatal("Failed to decode block: ", err.Error())
-	}
-
-	return &block
-}
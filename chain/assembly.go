@@ -0,0 +1,173 @@
+package chain
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/jplesperance/tcn/tx"
+)
+
+// A transaction being considered for inclusion in a block
+//
+// fee: value of the inputs not claimed by the outputs
+// size: bytes the transaction adds to the serialized block
+// parents: IDs of other candidates whose outputs it spends
+type blockCandidate struct {
+	tx      *tx.Transaction
+	fee     tx.Amount
+	size    int
+	sigOps  int
+	parents []string
+}
+
+// Return the fee paid per byte
+func (c *blockCandidate) feeRate() float64 {
+	return float64(c.fee) / float64(c.size)
+}
+
+// Return the fee paid by t, the total value of its inputs minus its
+// outputs.  Inputs are looked up in pending before the UTXO index.
+func (bc *Blockchain) TransactionFee(t *tx.Transaction, pending map[string]*tx.Transaction) (tx.Amount, error) {
+	if t.IsCoinbase() {
+		return 0, nil
+	}
+
+	var inputs tx.Amount
+	for _, vin := range t.Vin {
+		var value tx.Amount
+		if prevTX, ok := pending[hex.EncodeToString(vin.Txid)]; ok {
+			if vin.Vout < 0 || vin.Vout >= len(prevTX.Vout) {
+				return 0, fmt.Errorf("transaction %x spends missing output %x:%d", t.ID, vin.Txid, vin.Vout)
+			}
+			value = prevTX.Vout[vin.Vout].Value
+		} else {
+			utxo, err := bc.GetUTXO(vin.Txid, vin.Vout)
+			if err != nil {
+				return 0, err
+			}
+			if utxo == nil {
+				return 0, fmt.Errorf("%w: transaction %x spends missing output %x:%d", tx.ErrTxNotFound, t.ID, vin.Txid, vin.Vout)
+			}
+			value = utxo.Output.Value
+		}
+		var err error
+		inputs, err = inputs.Add(value)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	outputs, err := t.OutputValue()
+	if err != nil {
+		return 0, err
+	}
+
+	return inputs.Sub(outputs)
+}
+
+// AssembleBlock picks the transactions for a new block from candidates and
+// returns them with the total fee they pay.
+//
+// Transactions are taken in order of decreasing fee rate while they fit
+// within the block size and signature operation limits of the network,
+// leaving room for the header and for coinbase, whose value may still grow
+// by the fees.  A transaction spending another candidate is only taken
+// after its parent.  Candidates whose inputs cannot be found are skipped.
+func (bc *Blockchain) AssembleBlock(coinbase *tx.Transaction, candidates []*tx.Transaction) ([]*tx.Transaction, tx.Amount) {
+	pending := make(map[string]*tx.Transaction)
+	for _, tx := range candidates {
+		pending[hex.EncodeToString(tx.ID)] = tx
+	}
+
+	var sorted []*blockCandidate
+	for _, tx := range candidates {
+		fee, err := bc.TransactionFee(tx, pending)
+		if err != nil {
+			continue
+		}
+
+		c := &blockCandidate{tx: tx, fee: fee, sigOps: tx.SigOpCount()}
+		c.size = tx.SerializedSize() + uvarintSize(uint64(tx.SerializedSize()))
+		for _, vin := range tx.Vin {
+			if _, ok := pending[hex.EncodeToString(vin.Txid)]; ok {
+				c.parents = append(c.parents, hex.EncodeToString(vin.Txid))
+			}
+		}
+		sorted = append(sorted, c)
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].feeRate() > sorted[j].feeRate()
+	})
+
+	header := BlockHeader{Version: blockVersion, PrevBlockHash: make([]byte, 32), MerkleRoot: make([]byte, 32)}
+	headerSize := maxHeaderSize(header)
+	coinbaseSize := coinbase.SerializedSize() + 2*binary.MaxVarintLen64
+	size := headerSize + uvarintSize(uint64(headerSize)) + binary.MaxVarintLen64 + coinbaseSize
+	sigOps := coinbase.SigOpCount()
+
+	var selected []*tx.Transaction
+	var fees tx.Amount
+	included := make(map[string]bool)
+
+	for progress := true; progress; {
+		progress = false
+
+	Candidates:
+		for _, c := range sorted {
+			id := hex.EncodeToString(c.tx.ID)
+			if included[id] {
+				continue
+			}
+			if size+c.size > bc.params.MaxBlockSize || sigOps+c.sigOps > bc.params.MaxBlockSigOps {
+				continue
+			}
+			for _, parent := range c.parents {
+				if !included[parent] {
+					continue Candidates
+				}
+			}
+			total, err := fees.Add(c.fee)
+			if err != nil {
+				continue
+			}
+
+			selected = append(selected, c.tx)
+			included[id] = true
+			size += c.size
+			sigOps += c.sigOps
+			fees = total
+			progress = true
+		}
+	}
+
+	return selected, fees
+}
+
+// CheckFits returns ErrTxTooLarge when t would be left out of a block mined
+// on top of the tip for rewardAddress because it exceeds the block size or
+// signature operation limits of the network
+func (bc *Blockchain) CheckFits(t *tx.Transaction, rewardAddress string) error {
+	header, err := bc.GetHeader(bc.Tip())
+	if err != nil {
+		return err
+	}
+	coinbase, err := tx.NewCoinbaseTX(rewardAddress, coinbaseData(rewardAddress, header.Height+1), 0)
+	if err != nil {
+		return err
+	}
+	if selected, _ := bc.AssembleBlock(coinbase, []*tx.Transaction{t}); len(selected) == 0 {
+		return fmt.Errorf("%w: %x", ErrTxTooLarge, t.ID)
+	}
+
+	return nil
+}
+
+// Return the number of bytes needed to encode v as a uvarint
+func uvarintSize(v uint64) int {
+	var scratch [binary.MaxVarintLen64]byte
+
+	return binary.PutUvarint(scratch[:], v)
+}
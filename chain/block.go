@@ -0,0 +1,289 @@
+package chain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/jplesperance/tcn/consensus/pow"
+	"github.com/jplesperance/tcn/encoding/canonical"
+	"github.com/jplesperance/tcn/network"
+	"github.com/jplesperance/tcn/tx"
+)
+
+// Block format versions.  Legacy blocks were hashed with an earlier scheme
+// and keep the hash they were stored under:
+//
+// 0: migrated from the legacy gob encoding
+// 2: the header is hashed
+//
+// Version 1 was an intermediate format and is no longer accepted.
+const (
+	legacyBlockVersion = 0
+	blockVersion       = 2
+)
+
+// Represents the header of a block, the part covered by proof of work
+//
+// Version: the block format version
+// PrevBlockHash: the hash of the previous block
+// MerkleRoot: the merkle root of the block's transaction hashes
+// Timestamp: the timestamp for when the block was created
+// Bits: the number of leading zero bits the block hash must have
+// Nonce: the value found by proof of work
+// Height: the number of blocks preceding this one in the chain
+type BlockHeader struct {
+	Version       int
+	PrevBlockHash []byte
+	MerkleRoot    []byte
+	Timestamp     int64
+	Bits          int
+	Nonce         int
+	Height        int
+}
+
+// Represents a block in the blockchain
+//
+// BlockHeader: the header of the block
+// Hash: the hash of the current block
+// Transactions: actual information contained in the block
+type Block struct {
+	BlockHeader
+	Hash         []byte
+	Transactions []*tx.Transaction
+}
+
+// Return the merkle root of the block's transaction hashes
+func (b *Block) HashTransactions() []byte {
+	var txHashes [][]byte
+
+	for _, tx := range b.Transactions {
+		txHashes = append(txHashes, tx.Hash())
+	}
+
+	return MerkleRoot(txHashes)
+}
+
+// Compute the merkle root of a list of hashes.  Each level hashes adjacent
+// pairs, pairing the last hash with itself when a level has an odd length.
+func MerkleRoot(hashes [][]byte) []byte {
+	if len(hashes) == 0 {
+		root := sha256.Sum256([]byte{})
+		return root[:]
+	}
+
+	level := hashes
+	for len(level) > 1 {
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			right := level[i]
+			if i+1 < len(level) {
+				right = level[i+1]
+			}
+			node := sha256.Sum256(bytes.Join([][]byte{level[i], right}, []byte{}))
+			next = append(next, node[:])
+		}
+		level = next
+	}
+
+	return level[0]
+}
+
+// Return the hash of the header
+func (h *BlockHeader) Hash() []byte {
+	hash := sha256.Sum256(h.Serialize())
+
+	return hash[:]
+}
+
+// ProofOfWork returns the proof of work over the header, hashing its
+// serialization with each candidate nonce
+func (h *BlockHeader) ProofOfWork() *pow.ProofOfWork {
+	return pow.NewProofOfWork(h.Bits, func(nonce int) []byte {
+		header := *h
+		header.Nonce = nonce
+
+		return header.Serialize()
+	})
+}
+
+// CheckProofOfWork validates the header's nonce against its proof of work
+//
+// Headers older than blockVersion were hashed with earlier schemes and
+// cannot be validated.
+func (h *BlockHeader) CheckProofOfWork() bool {
+	if h.Version != blockVersion || h.Bits != pow.TargetBits {
+		return false
+	}
+
+	return h.ProofOfWork().Validate(h.Nonce)
+}
+
+// Serialize the header into its canonical binary encoding
+func (h *BlockHeader) Serialize() []byte {
+	var e canonical.Encoder
+	h.encode(&e)
+
+	return e.Bytes()
+}
+
+func (h *BlockHeader) encode(e *canonical.Encoder) {
+	e.WriteUvarint(uint64(h.Version))
+	e.WriteBytes(h.PrevBlockHash)
+	e.WriteBytes(h.MerkleRoot)
+	e.WriteVarint(h.Timestamp)
+	e.WriteUvarint(uint64(h.Bits))
+	e.WriteUvarint(uint64(h.Nonce))
+	e.WriteUvarint(uint64(h.Height))
+}
+
+// Decode a header from its canonical binary encoding
+func DeserializeBlockHeader(data []byte) (*BlockHeader, error) {
+	var header BlockHeader
+	d := canonical.NewDecoder(data)
+	header.decode(d)
+	if err := d.Finish(); err != nil {
+		return nil, fmt.Errorf("failed to decode block header: %v", err)
+	}
+
+	return &header, nil
+}
+
+func (h *BlockHeader) decode(d *canonical.Decoder) {
+	// compared before converting, so that a huge version cannot wrap
+	version := d.ReadUvarint()
+	if d.Err() == nil && version != legacyBlockVersion && version != blockVersion {
+		d.Fail(fmt.Errorf("unsupported block version %d", version))
+		return
+	}
+	h.Version = int(version)
+	h.PrevBlockHash = d.ReadBytes()
+	h.MerkleRoot = d.ReadBytes()
+	h.Timestamp = d.ReadVarint()
+	h.Bits = d.ReadInt()
+	h.Nonce = d.ReadInt()
+	h.Height = d.ReadInt()
+}
+
+// Serialize the block into its canonical binary encoding, the header
+// followed by the transactions
+func (b *Block) Serialize() []byte {
+	var e canonical.Encoder
+	e.WriteBytes(b.BlockHeader.Serialize())
+	e.Write(SerializeTransactions(b.Transactions))
+
+	return e.Bytes()
+}
+
+// Serialize a block body, the list of its transactions
+func SerializeTransactions(transactions []*tx.Transaction) []byte {
+	var e canonical.Encoder
+	e.WriteUvarint(uint64(len(transactions)))
+	for _, tx := range transactions {
+		e.WriteBytes(tx.Serialize())
+	}
+
+	return e.Bytes()
+}
+
+// Decode a block body produced by SerializeTransactions
+func DeserializeTransactions(data []byte) ([]*tx.Transaction, error) {
+	d := canonical.NewDecoder(data)
+	transactions := decodeTransactions(d)
+	if err := d.Finish(); err != nil {
+		return nil, fmt.Errorf("failed to decode block transactions: %v", err)
+	}
+
+	return transactions, nil
+}
+
+func decodeTransactions(d *canonical.Decoder) []*tx.Transaction {
+	transactions := make([]*tx.Transaction, d.ReadCount())
+	for i := range transactions {
+		t, err := tx.DeserializeTransaction(d.ReadBytes())
+		if d.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			d.Fail(err)
+			return nil
+		}
+		transactions[i] = &t
+	}
+
+	return transactions
+}
+
+// Create a new block, populate the fields and return it to the calling method
+//
+// The block is stamped with the current time, or medianTimePast+1 if the
+// clock is behind the chain.
+func NewBlock(transactions []*tx.Transaction, prevBlockHash []byte, height int, medianTimePast int64) *Block {
+	timestamp := time.Now().Unix()
+	if height > 0 && timestamp <= medianTimePast {
+		timestamp = medianTimePast + 1
+	}
+
+	header := BlockHeader{blockVersion, prevBlockHash, nil, timestamp, pow.TargetBits, 0, height}
+	block := &Block{header, []byte{}, transactions}
+	block.MerkleRoot = block.HashTransactions()
+
+	proof := block.BlockHeader.ProofOfWork()
+	nonce, hash := proof.Run()
+
+	block.Hash = hash[:]
+	block.Nonce = nonce
+
+	return block
+}
+
+// NewGenesisBlock returns the genesis block of a network, the first block
+// of its blockchain.  It is built from the network parameters alone, so that
+// every node creates the same block, and its coinbase pays the subsidy to an
+// output nobody can spend.  Networks with a legacy genesis get the legacy
+// block their chain started from.
+func NewGenesisBlock(params *network.Params) *Block {
+	if params.LegacyGenesis {
+		return legacyGenesisBlock()
+	}
+
+	coinbase := &tx.Transaction{
+		Version: tx.CurrentVersion,
+		Vin:     []tx.TXInput{{Txid: []byte{}, Vout: -1, PubKey: []byte(genesisCoinbaseData)}},
+		Vout:    []tx.TXOutput{{Value: tx.Subsidy, PubKeyHash: make([]byte, 20), Type: tx.OutputPubKeyHash}},
+	}
+	coinbase.ID = coinbase.ComputeID()
+
+	header := BlockHeader{blockVersion, []byte{}, nil, params.GenesisTime, pow.TargetBits, params.GenesisNonce, 0}
+	block := &Block{header, []byte{}, []*tx.Transaction{coinbase}}
+	block.MerkleRoot = block.HashTransactions()
+	block.Hash = block.BlockHeader.Hash()
+
+	return block
+}
+
+// Take the byte array, decode the block and return the struct
+func DeserializeBlock(d []byte) (*Block, error) {
+	return decodeBlock(d)
+}
+
+func decodeBlock(data []byte) (*Block, error) {
+	var block Block
+	d := canonical.NewDecoder(data)
+
+	header, err := DeserializeBlockHeader(d.ReadBytes())
+	if d.Err() == nil && err != nil {
+		return nil, err
+	}
+	block.Transactions = decodeTransactions(d)
+
+	if err := d.Finish(); err != nil {
+		return nil, fmt.Errorf("failed to decode block: %v", err)
+	}
+
+	block.BlockHeader = *header
+	block.Hash = header.Hash()
+
+	return &block, nil
+}
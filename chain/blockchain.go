@@ -0,0 +1,506 @@
+// Package chain stores and validates the blockchain
+package chain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/jplesperance/tcn/encoding/canonical"
+	"github.com/jplesperance/tcn/logging"
+	"github.com/jplesperance/tcn/network"
+	"github.com/jplesperance/tcn/tx"
+	"github.com/jplesperance/tcn/wallet"
+)
+
+var log = logging.New(logging.Chain)
+
+const blocksBucket = "blocks"
+const metaBucket = "meta"
+const dbFormatKey = "format"
+const networkKey = "network"
+
+// Version of the on-disk block encoding, bumped whenever stored data must be
+// rewritten by migratechain
+const dbFormatVersion = 5
+const genesisCoinbaseData = "The Times 03/Jan/2009 Chancellor on brink of second bailout for banks"
+
+// Options selects the database and network of a Blockchain
+//
+// DBFile: path of the database file, the DBFile of the network if empty
+// Params: parameters of the network the chain belongs to, network.Main if
+// nil
+type Options struct {
+	DBFile string
+	Params *network.Params
+}
+
+// Fill in the defaults of unset options
+func (o Options) withDefaults() Options {
+	if o.Params == nil {
+		o.Params = &network.Main
+	}
+	if o.DBFile == "" {
+		o.DBFile = o.Params.DBFile
+	}
+
+	return o
+}
+
+// Blockchaing implements interactions with a DB
+type Blockchain struct {
+	tip    []byte
+	db     *bolt.DB
+	params *network.Params
+}
+
+// Params returns the parameters of the network the chain belongs to
+func (bc *Blockchain) Params() *network.Params {
+	return bc.params
+}
+
+type BlockchainIterator struct {
+	currentHash []byte
+	db          *bolt.DB
+}
+
+func (bc *Blockchain) Iterator() *BlockchainIterator {
+	bci := &BlockchainIterator{bc.tip, bc.db}
+
+	return bci
+}
+
+// Close releases the database backing the blockchain
+func (bc *Blockchain) Close() error {
+	return bc.db.Close()
+}
+
+// Tip returns the hash of the last block in the chain
+func (bc *Blockchain) Tip() []byte {
+	return bc.tip
+}
+
+func (bc *Blockchain) SignTransaction(tx *tx.Transaction, privKey ecdsa.PrivateKey) error {
+	prevTXs, err := bc.prevTransactions(tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Sign(privKey, prevTXs)
+}
+
+func (bc *Blockchain) VerifyTransaction(tx *tx.Transaction) error {
+	if tx.IsCoinbase() {
+		return nil
+	}
+
+	prevTXs, err := bc.prevTransactions(tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Verify(prevTXs)
+}
+
+func (i *BlockchainIterator) Next() (*Block, error) {
+	var block *Block
+
+	err := i.db.View(func(tx *bolt.Tx) error {
+		var err error
+		block, err = getBlock(tx, i.currentHash)
+		return err
+	})
+
+	if errors.Is(err, ErrBlockPruned) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve the blockchain from the database: %v", err)
+	}
+
+	i.currentHash = block.PrevBlockHash
+
+	return block, nil
+}
+
+// MineBlock mines a block on top of the tip and appends it to the chain.
+// Its transactions are picked from candidates by AssembleBlock, after a
+// coinbase paying the block subsidy and their fees to rewardAddress.
+// Candidates that do not fit are left out of the returned block.
+func (bc *Blockchain) MineBlock(candidates []*tx.Transaction, rewardAddress string) (*Block, error) {
+	var lastHash []byte
+	var lastHeight int
+	var lastMedianTime int64
+
+	if !wallet.ValidateAddress(rewardAddress) {
+		return nil, fmt.Errorf("%w: %s", wallet.ErrInvalidAddress, rewardAddress)
+	}
+	if err := bc.ValidateTransactions(candidates); err != nil {
+		return nil, err
+	}
+
+	err := bc.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(blocksBucket))
+		lastHash = append([]byte{}, b.Get([]byte("l"))...)
+
+		lastHeader, err := getHeader(tx, lastHash)
+		if err != nil {
+			return err
+		}
+		lastHeight = lastHeader.Height
+
+		lastMedianTime, err = medianTimePast(tx, lastHash)
+		return err
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve blockchain from the database: %v", err)
+	}
+	if err := checkForkBelowCheckpoint(bc.params, lastHeight+1); err != nil {
+		return nil, err
+	}
+
+	data := coinbaseData(rewardAddress, lastHeight+1)
+	coinbase, err := tx.NewCoinbaseTX(rewardAddress, data, 0)
+	if err != nil {
+		return nil, err
+	}
+	selected, fees := bc.AssembleBlock(coinbase, candidates)
+	coinbase, err = tx.NewCoinbaseTX(rewardAddress, data, fees)
+	if err != nil {
+		return nil, err
+	}
+	transactions := append([]*tx.Transaction{coinbase}, selected...)
+
+	newBlock := NewBlock(transactions, lastHash, lastHeight+1, lastMedianTime)
+	if err := CheckBlockTime(&newBlock.BlockHeader, lastMedianTime, time.Now(), bc.params); err != nil {
+		return nil, err
+	}
+	if err := CheckBlockLimits(newBlock, bc.params); err != nil {
+		return nil, err
+	}
+
+	if err := bc.appendBlock(newBlock); err != nil {
+		return nil, err
+	}
+	log.Info("added block", "hash", hex.EncodeToString(newBlock.Hash), "height", newBlock.Height, "transactions", len(newBlock.Transactions), "fees", fees)
+
+	return newBlock, nil
+}
+
+// Return the coinbase data of the block at height paying rewardAddress.  The
+// height keeps coinbases paying the same address apart.
+func coinbaseData(rewardAddress string, height int) string {
+	return fmt.Sprintf("Reward to '%s' at height %d", rewardAddress, height)
+}
+
+// NewBlockchain opens the existing blockchain selected by opts
+func NewBlockchain(opts Options) (*Blockchain, error) {
+	opts = opts.withDefaults()
+	if dbExists(opts.DBFile) == false {
+		return nil, ErrChainNotFound
+	}
+
+	var tip []byte
+	db, err := bolt.Open(opts.DBFile, 0600, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to open database: %v", err)
+	}
+
+	format, err := dbFormat(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if format != dbFormatVersion {
+		db.Close()
+		return nil, ErrOutdatedFormat
+	}
+	name, err := dbNetwork(db)
+	if err == nil && name != "" && name != opts.Params.Name {
+		err = fmt.Errorf("the database %s belongs to the %s network, not %s", opts.DBFile, name, opts.Params.Name)
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(blocksBucket))
+		tip = append([]byte{}, b.Get([]byte("l"))...)
+		return nil
+	})
+
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	log.Debug("opened blockchain", "file", opts.DBFile, "network", opts.Params.Name, "tip", hex.EncodeToString(tip))
+
+	bc := Blockchain{tip, db, opts.Params}
+	return &bc, nil
+}
+
+// Walk the chain from the tip back to genesis calling fn for every block
+func (bc *Blockchain) forEachBlock(fn func(block *Block) error) error {
+	bci := bc.Iterator()
+
+	for {
+		block, err := bci.Next()
+		if err != nil {
+			return err
+		}
+		if err := fn(block); err != nil {
+			return err
+		}
+
+		if len(block.PrevBlockHash) == 0 {
+			return nil
+		}
+	}
+}
+
+func (bc *Blockchain) FindUnspentTransactions(pubKeyHash []byte) ([]tx.Transaction, error) {
+	var unspentTXs []tx.Transaction
+	spentTXOs := make(map[string][]int)
+
+	err := bc.forEachBlock(func(block *Block) error {
+		for _, tx := range block.Transactions {
+
+			txID := hex.EncodeToString(tx.ID)
+
+		Outputs:
+			for outIdx, out := range tx.Vout {
+				if spentTXOs[txID] != nil {
+					for _, spentOutIdx := range spentTXOs[txID] {
+						if spentOutIdx == outIdx {
+							continue Outputs
+						}
+					}
+				}
+
+				if out.IsLockedWithKey(pubKeyHash) {
+					unspentTXs = append(unspentTXs, *tx)
+				}
+			}
+
+			if tx.IsCoinbase() == false {
+				for _, in := range tx.Vin {
+					if in.UsesKey(pubKeyHash) {
+						inTxID := hex.EncodeToString(in.Txid)
+						spentTXOs[inTxID] = append(spentTXOs[inTxID], in.Vout)
+					}
+				}
+			}
+		}
+		return nil
+	})
+
+	return unspentTXs, err
+}
+
+// Select outputs locked to any of the given public key hashes covering amount
+// using the given coin selection strategy
+func (bc *Blockchain) FindSpendableOutputs(pubKeyHashes [][]byte, amount tx.Amount, selector tx.CoinSelector) (tx.Amount, []tx.UTXO, error) {
+	candidates, err := bc.FindUTXOs(pubKeyHashes)
+	if err != nil {
+		return 0, nil, err
+	}
+	selected, accumulated := selector.Select(candidates, amount)
+
+	return accumulated, selected, nil
+}
+
+func (bc *Blockchain) FindTransaction(ID []byte) (tx.Transaction, error) {
+	var found *tx.Transaction
+
+	// errStop ends the walk early once the transaction is found
+	errStop := errors.New("stop")
+
+	err := bc.forEachBlock(func(block *Block) error {
+		for _, tx := range block.Transactions {
+			if bytes.Compare(tx.ID, ID) == 0 {
+				found = tx
+				return errStop
+			}
+		}
+		return nil
+	})
+
+	if found != nil {
+		return *found, nil
+	}
+	if err != nil {
+		return tx.Transaction{}, err
+	}
+	return tx.Transaction{}, fmt.Errorf("%w: %x", tx.ErrTxNotFound, ID)
+}
+
+// Return the storage format version of the database, 0 for the legacy
+// gob format which predates the meta bucket
+func dbFormat(db *bolt.DB) (uint64, error) {
+	var format uint64
+
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(metaBucket))
+		if b == nil {
+			return nil
+		}
+		d := canonical.NewDecoder(b.Get([]byte(dbFormatKey)))
+		format = d.ReadUvarint()
+
+		return d.Finish()
+	})
+
+	if err != nil {
+		return 0, fmt.Errorf("unable to read the database format: %v", err)
+	}
+
+	return format, nil
+}
+
+func putDBFormat(tx *bolt.Tx, format uint64) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(metaBucket))
+	if err != nil {
+		return err
+	}
+
+	var e canonical.Encoder
+	e.WriteUvarint(format)
+
+	return b.Put([]byte(dbFormatKey), e.Bytes())
+}
+
+// Return the name of the network the database belongs to, or "" for
+// databases created before it was recorded
+func dbNetwork(db *bolt.DB) (string, error) {
+	var name string
+
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(metaBucket))
+		if b == nil {
+			return nil
+		}
+		name = string(b.Get([]byte(networkKey)))
+
+		return nil
+	})
+
+	return name, err
+}
+
+func putNetwork(tx *bolt.Tx, name string) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(metaBucket))
+	if err != nil {
+		return err
+	}
+
+	return b.Put([]byte(networkKey), []byte(name))
+}
+
+func dbExists(path string) bool {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return false
+	}
+
+	return true
+}
+
+// CreateBlockchain creates the blockchain selected by opts from the genesis
+// block of its network, then mines a first block that rewards address.
+// Networks whose genesis block is checkpointed under another hash cannot be
+// created anew.
+func CreateBlockchain(opts Options, address string) (*Blockchain, error) {
+	opts = opts.withDefaults()
+	if dbExists(opts.DBFile) {
+		return nil, ErrChainExists
+	}
+	if !wallet.ValidateAddress(address) {
+		return nil, wallet.ErrInvalidAddress
+	}
+
+	genesis := NewGenesisBlock(opts.Params)
+	if err := checkCheckpoint(opts.Params, 0, genesis.Hash); err != nil {
+		return nil, fmt.Errorf("the %s network cannot be created anew: %w", opts.Params.Name, err)
+	}
+
+	var tip []byte
+
+	db, err := bolt.Open(opts.DBFile, 0600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open database: %v", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+
+		b, err := tx.CreateBucket([]byte(blocksBucket))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateBucket([]byte(headersBucket))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateBucket([]byte(utxoBucket))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateBucket([]byte(undoBucket))
+		if err != nil {
+			return err
+		}
+
+		err = putBlock(tx, genesis)
+		if err != nil {
+			return err
+		}
+
+		err = connectBlock(tx, genesis)
+		if err != nil {
+			return err
+		}
+
+		err = b.Put([]byte("l"), genesis.Hash)
+		if err != nil {
+			return err
+		}
+
+		err = putDBFormat(tx, dbFormatVersion)
+		if err != nil {
+			return err
+		}
+
+		err = putNetwork(tx, opts.Params.Name)
+		if err != nil {
+			return err
+		}
+
+		tip = genesis.Hash
+
+		return nil
+	})
+
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	log.Info("created blockchain", "file", opts.DBFile, "network", opts.Params.Name, "genesis", hex.EncodeToString(genesis.Hash))
+
+	bc := Blockchain{tip, db, opts.Params}
+	if _, err := bc.MineBlock(nil, address); err != nil {
+		db.Close()
+		os.Remove(opts.DBFile)
+		return nil, err
+	}
+
+	return &bc, nil
+}
@@ -0,0 +1,450 @@
+package chain
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/jplesperance/tcn/encoding/canonical"
+	"github.com/jplesperance/tcn/network"
+	"github.com/jplesperance/tcn/tx"
+)
+
+// Bootstrap files hold a range of blocks in height order so that a chain can
+// be archived or used to seed a new node independently of the database
+// format.  See docs/serialization.md for the layout.
+var bootstrapMagic = []byte("TCNB")
+
+const bootstrapVersion = 1
+
+// room for the hash and length prefixes of a block record on top of the
+// maximum block size
+const bootstrapRecordOverhead = 1024
+
+// number of blocks between progress records
+const bootstrapProgressInterval = 1000
+
+// ExportChain writes the blocks with heights from through to, inclusive, to
+// w as a bootstrap stream and returns the number of blocks written.  A
+// negative to exports up to the tip.  Only streams starting at genesis can
+// be imported; a range starting higher is an archive of those blocks.
+func (bc *Blockchain) ExportChain(w io.Writer, from, to int) (int, error) {
+	var hashes [][]byte
+
+	hi := bc.HeaderIterator()
+	for {
+		header, hash, err := hi.Next()
+		if err != nil {
+			return 0, err
+		}
+		if header == nil {
+			break
+		}
+		hashes = append(hashes, hash)
+	}
+
+	// hashes run from the tip back to genesis
+	tipHeight := len(hashes) - 1
+	if to < 0 {
+		to = tipHeight
+	}
+	if from < 0 || to > tipHeight || from > to {
+		return 0, fmt.Errorf("invalid block range %d to %d, the tip is at height %d", from, to, tipHeight)
+	}
+
+	bw := bufio.NewWriter(w)
+	count := to - from + 1
+
+	var header canonical.Encoder
+	header.Write(bootstrapMagic)
+	header.WriteUvarint(bootstrapVersion)
+	header.WriteUvarint(uint64(count))
+	if _, err := bw.Write(header.Bytes()); err != nil {
+		return 0, err
+	}
+
+	for height := from; height <= to; height++ {
+		block, err := bc.GetBlock(hashes[tipHeight-height])
+		if err != nil {
+			return height - from, err
+		}
+
+		var record canonical.Encoder
+		record.WriteBytes(block.Hash)
+		record.WriteBytes(block.Serialize())
+
+		var e canonical.Encoder
+		e.WriteBytes(record.Bytes())
+		if _, err := bw.Write(e.Bytes()); err != nil {
+			return height - from, err
+		}
+
+		if exported := height - from + 1; exported%bootstrapProgressInterval == 0 {
+			log.Info("exported blocks", "height", height, "exported", exported, "total", count)
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return count, err
+	}
+	log.Info("exported chain", "from", from, "to", to, "blocks", count)
+
+	return count, nil
+}
+
+// ImportChain creates the blockchain selected by opts from a bootstrap
+// stream starting at the genesis block.  Every block is validated as if it
+// had been received from a peer: proof of work, linkage, checkpoints,
+// timestamps, limits, merkle root, coinbase and transactions, which must all
+// be of tx.CurrentVersion.  Blocks older than blockVersion are only accepted
+// at or below the last checkpoint, before any newer block, and trusted as
+// stored.  If any block is invalid
+// the partially written database is removed.
+//
+// When assumeValid is set, signatures of the ancestors of the block with
+// that hash are not verified; everything else still is.  If the stream does
+// not contain that block the skipped signatures are verified once all
+// blocks are imported.
+func ImportChain(opts Options, r io.Reader, assumeValid []byte) (*Blockchain, int, error) {
+	opts = opts.withDefaults()
+	if dbExists(opts.DBFile) {
+		return nil, 0, ErrChainExists
+	}
+
+	br := bufio.NewReader(r)
+	count, err := readBootstrapHeader(br)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	db, err := bolt.Open(opts.DBFile, 0600, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to open database: %v", err)
+	}
+
+	bc := &Blockchain{nil, db, opts.Params}
+	imported, err := bc.importBlocks(br, count, assumeValid)
+	if err != nil {
+		db.Close()
+		os.Remove(opts.DBFile)
+		return nil, imported, err
+	}
+	log.Info("imported chain", "blocks", imported, "tip", hex.EncodeToString(bc.tip))
+
+	return bc, imported, nil
+}
+
+func readBootstrapHeader(r *bufio.Reader) (int, error) {
+	magic := make([]byte, len(bootstrapMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || !bytes.Equal(magic, bootstrapMagic) {
+		return 0, errors.New("not a bootstrap file")
+	}
+
+	version, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read bootstrap file: %v", err)
+	}
+	if version != bootstrapVersion {
+		return 0, fmt.Errorf("unsupported bootstrap file version %d", version)
+	}
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read bootstrap file: %v", err)
+	}
+	if count == 0 {
+		return 0, errors.New("bootstrap file holds no blocks")
+	}
+
+	return int(count), nil
+}
+
+// Read the next block record from a bootstrap stream of a network with the
+// given parameters
+func readBootstrapBlock(r *bufio.Reader, params *network.Params) (*Block, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if max := uint64(params.MaxBlockSize + bootstrapRecordOverhead); size > max {
+		return nil, fmt.Errorf("block record of %d bytes exceeds the maximum of %d", size, max)
+	}
+
+	record := make([]byte, size)
+	if _, err := io.ReadFull(r, record); err != nil {
+		return nil, err
+	}
+
+	d := canonical.NewDecoder(record)
+	hash := d.ReadBytes()
+	encoded := d.ReadBytes()
+	if err := d.Finish(); err != nil {
+		return nil, err
+	}
+
+	block, err := DeserializeBlock(encoded)
+	if err != nil {
+		return nil, err
+	}
+	// the hash of blocks older than blockVersion cannot be recomputed, the
+	// others keep the hash of their header
+	if block.Version < blockVersion {
+		block.Hash = hash
+	}
+
+	return block, nil
+}
+
+// number of blocks validated and stored per database transaction during an
+// import, so that the database is not synced after every block
+const importBatchSize = 500
+
+// blockImport holds the progress of importing a bootstrap stream
+//
+// verifySignatures: whether signatures are checked, set once the assumed
+// valid block has been passed
+// skipped: number of blocks whose signatures were not checked
+// imported: number of blocks stored so far
+// prev, prevHash: header and hash of the last block stored
+// timestamps: timestamps of the last medianTimeSpan blocks stored
+type blockImport struct {
+	bc               *Blockchain
+	r                *bufio.Reader
+	count            int
+	assumeValid      []byte
+	now              time.Time
+	verifySignatures bool
+	skipped          int
+	imported         int
+	prev             *BlockHeader
+	prevHash         []byte
+	timestamps       []int64
+}
+
+func (bc *Blockchain) importBlocks(r *bufio.Reader, count int, assumeValid []byte) (int, error) {
+	if err := bc.db.Update(bc.createBuckets); err != nil {
+		return 0, err
+	}
+
+	imp := &blockImport{
+		bc:               bc,
+		r:                r,
+		count:            count,
+		assumeValid:      assumeValid,
+		now:              time.Now(),
+		verifySignatures: len(assumeValid) == 0,
+	}
+	for imp.imported < count {
+		if err := bc.db.Update(imp.importBatch); err != nil {
+			return imp.imported, err
+		}
+		bc.tip = imp.prevHash
+	}
+
+	if _, err := r.ReadByte(); err != io.EOF {
+		return count, errors.New("unexpected data after the last block of the bootstrap file")
+	}
+
+	if !imp.verifySignatures && imp.skipped > 0 {
+		log.Warn("the assumed valid block is not in the bootstrap file, verifying skipped signatures", "hash", hex.EncodeToString(assumeValid), "blocks", imp.skipped)
+		if err := bc.verifySignatures(); err != nil {
+			return count, err
+		}
+	}
+
+	return count, nil
+}
+
+// Create the buckets and metadata of a new, empty chain
+func (bc *Blockchain) createBuckets(btx *bolt.Tx) error {
+	for _, name := range []string{blocksBucket, headersBucket, utxoBucket, undoBucket} {
+		if _, err := btx.CreateBucket([]byte(name)); err != nil {
+			return err
+		}
+	}
+	if err := putDBFormat(btx, dbFormatVersion); err != nil {
+		return err
+	}
+
+	return putNetwork(btx, bc.params.Name)
+}
+
+// Import up to importBatchSize blocks in btx
+func (imp *blockImport) importBatch(btx *bolt.Tx) error {
+	for n := 0; n < importBatchSize && imp.imported < imp.count; n++ {
+		if err := imp.importBlock(btx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Read the next block of the stream, validate it and store it in btx
+func (imp *blockImport) importBlock(btx *bolt.Tx) error {
+	block, err := readBootstrapBlock(imp.r, imp.bc.params)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read block %d of %d: %v", imp.imported+1, imp.count, err)
+	}
+	if imp.imported == 0 && block.Height != 0 {
+		return fmt.Errorf("the bootstrap file starts at height %d, only a file starting at the genesis block can be imported", block.Height)
+	}
+
+	if !imp.verifySignatures && bytes.Equal(block.Hash, imp.assumeValid) {
+		imp.verifySignatures = true
+		log.Info("reached the assumed valid block", "hash", hex.EncodeToString(block.Hash), "height", block.Height, "skipped", imp.skipped)
+	}
+	if err := imp.bc.validateBlock(btx, block, imp.prev, imp.prevHash, medianTime(imp.timestamps), imp.now, imp.verifySignatures); err != nil {
+		return err
+	}
+	if block.Version >= blockVersion {
+		if err := checkTransactionVersions(block.Transactions); err != nil {
+			return fmt.Errorf("block %x: %w", block.Hash, err)
+		}
+		if !imp.verifySignatures {
+			imp.skipped++
+		}
+	}
+	if err := extendChain(btx, block); err != nil {
+		return err
+	}
+
+	imp.prev, imp.prevHash = &block.BlockHeader, block.Hash
+	imp.timestamps = append(imp.timestamps, block.Timestamp)
+	if len(imp.timestamps) > medianTimeSpan {
+		imp.timestamps = imp.timestamps[1:]
+	}
+	imp.imported++
+
+	if imp.imported%bootstrapProgressInterval == 0 {
+		log.Info("imported blocks", "height", block.Height, "imported", imp.imported, "total", imp.count)
+	}
+
+	return nil
+}
+
+// Check a block extending prev, the header of the tip of btx or nil for
+// genesis, with the given median time past.  Transaction signatures are
+// only checked if verifySignatures is set.  Blocks older than blockVersion
+// are only accepted where ValidateHeader allows them, and trusted as
+// stored.
+func (bc *Blockchain) validateBlock(btx *bolt.Tx, block *Block, prev *BlockHeader, prevHash []byte, medianTimePast int64, now time.Time, verifySignatures bool) error {
+	if err := ValidateHeader(&block.BlockHeader, block.Hash, prev, prevHash, bc.params); err != nil {
+		return err
+	}
+	if block.Version < blockVersion {
+		return nil
+	}
+
+	if err := CheckBlockTime(&block.BlockHeader, medianTimePast, now, bc.params); err != nil {
+		return fmt.Errorf("block %x: %v", block.Hash, err)
+	}
+	if err := CheckBlockLimits(block, bc.params); err != nil {
+		return fmt.Errorf("block %x: %v", block.Hash, err)
+	}
+	if !bytes.Equal(block.MerkleRoot, block.HashTransactions()) {
+		return fmt.Errorf("block %x merkle root does not match its transactions", block.Hash)
+	}
+	fees, err := validateTransactions(btx, block.Transactions, verifySignatures)
+	if err != nil {
+		return fmt.Errorf("block %x: %w", block.Hash, err)
+	}
+	if err := checkCoinbase(block.Transactions, fees); err != nil {
+		return fmt.Errorf("block %x: %w", block.Hash, err)
+	}
+
+	return nil
+}
+
+// Verify the signatures of every transaction in the chain, walking it from
+// genesis to the tip
+func (bc *Blockchain) verifySignatures() error {
+	var hashes [][]byte
+
+	hi := bc.HeaderIterator()
+	for {
+		header, hash, err := hi.Next()
+		if err != nil {
+			return err
+		}
+		if header == nil {
+			break
+		}
+		hashes = append(hashes, hash)
+	}
+
+	prevTXs := make(map[string]tx.Transaction)
+	for i := len(hashes) - 1; i >= 0; i-- {
+		block, err := bc.GetBlock(hashes[i])
+		if err != nil {
+			return err
+		}
+
+		var signatures blockSignatures
+		for _, t := range block.Transactions {
+			if block.Version >= blockVersion {
+				if err := signatures.verifyTransaction(t, prevTXs); err != nil {
+					return fmt.Errorf("block %x: %w", block.Hash, err)
+				}
+			}
+			prevTXs[hex.EncodeToString(t.ID)] = *t
+		}
+		if err := signatures.verify(); err != nil {
+			return fmt.Errorf("block %x: %w", block.Hash, err)
+		}
+	}
+
+	return nil
+}
+
+// Store a validated block, apply it to the UTXO index and make it the new
+// tip, pruning old blocks if enabled
+func (bc *Blockchain) appendBlock(block *Block) error {
+	err := bc.db.Update(func(btx *bolt.Tx) error {
+		return extendChain(btx, block)
+	})
+	if err != nil {
+		return err
+	}
+	bc.tip = block.Hash
+
+	return nil
+}
+
+// Connect a validated block on top of the tip of btx and prune old blocks
+// if enabled
+func extendChain(btx *bolt.Tx, block *Block) error {
+	if err := connectTip(btx, block); err != nil {
+		return err
+	}
+
+	return prune(btx, block.Hash)
+}
+
+// Store a block extending the tip, apply it to the UTXO index and make it
+// the new tip
+func connectTip(tx *bolt.Tx, block *Block) error {
+	err := putBlock(tx, block)
+	if err != nil {
+		return fmt.Errorf("error storing block: %v", err)
+	}
+	if err := connectBlock(tx, block); err != nil {
+		return err
+	}
+	err = tx.Bucket([]byte(blocksBucket)).Put([]byte("l"), block.Hash)
+	if err != nil {
+		return fmt.Errorf("unable to add new block to blockchain: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package chain
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jplesperance/tcn/network"
+)
+
+// Check a block against the checkpoint of the network at its height, if any
+func checkCheckpoint(params *network.Params, height int, hash []byte) error {
+	checkpoint := params.Checkpoint(height)
+	if checkpoint != nil && !bytes.Equal(checkpoint, hash) {
+		return fmt.Errorf("%w: block %x at height %d, expected %x", ErrCheckpoint, hash, height, checkpoint)
+	}
+
+	return nil
+}
+
+// Refuse a new block at or below the last checkpoint of the network, as it
+// would fork the checkpointed chain
+func checkForkBelowCheckpoint(params *network.Params, height int) error {
+	last := params.LastCheckpoint()
+	if last != nil && height <= last.Height {
+		return fmt.Errorf("%w: a new block at height %d would fork below the checkpoint at height %d", ErrCheckpoint, height, last.Height)
+	}
+
+	return nil
+}
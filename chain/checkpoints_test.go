@@ -0,0 +1,146 @@
+package chain
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/jplesperance/tcn/network"
+	"github.com/jplesperance/tcn/wallet"
+)
+
+// Create a test network chain of a genesis block and one mined block in dir
+func createTestChain(t *testing.T, dir string, params *network.Params) *Blockchain {
+	t.Helper()
+
+	w, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{DBFile: filepath.Join(dir, "blockchain.db"), Params: params}
+	bc, err := CreateBlockchain(opts, string(w.GetAddress()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bc.Close() })
+
+	return bc
+}
+
+// Return params with a checkpoint pinning hash at height appended
+func withCheckpoint(params network.Params, height int, hash []byte) *network.Params {
+	params.Checkpoints = append(append([]network.Checkpoint(nil), params.Checkpoints...), network.Checkpoint{Height: height, Hash: hash})
+
+	return &params
+}
+
+func TestGenesisMatchesCheckpoint(t *testing.T) {
+	for _, params := range []*network.Params{&network.Main, &network.Test} {
+		genesis := NewGenesisBlock(params)
+
+		if !bytes.Equal(genesis.Hash, params.Checkpoint(0)) {
+			t.Fatalf("%s: genesis hash %x, checkpoint %x", params.Name, genesis.Hash, params.Checkpoint(0))
+		}
+		if err := ValidateHeader(&genesis.BlockHeader, genesis.Hash, nil, nil, params); err != nil {
+			t.Fatalf("%s: %v", params.Name, err)
+		}
+	}
+}
+
+// The main network starts from the legacy block, so a created chain and a
+// migrated one share their genesis block
+func TestCreateMainNetwork(t *testing.T) {
+	bc := createTestChain(t, t.TempDir(), &network.Main)
+
+	genesis, err := bc.GetBlock(network.Main.Checkpoint(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if genesis.Version != legacyBlockVersion {
+		t.Fatalf("genesis version %d, want %d", genesis.Version, legacyBlockVersion)
+	}
+	if _, err := bc.ValidateHeaders(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateRefusesCheckpointedGenesis(t *testing.T) {
+	w, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := network.Test
+	params.Checkpoints = []network.Checkpoint{{Height: 0, Hash: make([]byte, 32)}}
+	opts := Options{DBFile: filepath.Join(t.TempDir(), "blockchain.db"), Params: &params}
+
+	if _, err := CreateBlockchain(opts, string(w.GetAddress())); !errors.Is(err, ErrCheckpoint) {
+		t.Fatalf("got %v, want %v", err, ErrCheckpoint)
+	}
+	if dbExists(opts.DBFile) {
+		t.Fatal("the refused chain left a database behind")
+	}
+}
+
+func TestForkBelowCheckpointRejected(t *testing.T) {
+	params := network.Test
+	pinned := createTestChain(t, t.TempDir(), &params)
+	fork := createTestChain(t, t.TempDir(), &params)
+
+	pinnedTip, err := pinned.GetBlock(pinned.Tip())
+	if err != nil {
+		t.Fatal(err)
+	}
+	forkTip, err := fork.GetBlock(fork.Tip())
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesis := NewGenesisBlock(&params)
+	if bytes.Equal(pinnedTip.Hash, forkTip.Hash) {
+		t.Fatal("the chains did not fork")
+	}
+
+	checkpointed := withCheckpoint(params, 1, pinnedTip.Hash)
+
+	t.Run("header", func(t *testing.T) {
+		if err := ValidateHeader(&pinnedTip.BlockHeader, pinnedTip.Hash, &genesis.BlockHeader, genesis.Hash, checkpointed); err != nil {
+			t.Fatalf("checkpointed block: %v", err)
+		}
+		err := ValidateHeader(&forkTip.BlockHeader, forkTip.Hash, &genesis.BlockHeader, genesis.Hash, checkpointed)
+		if !errors.Is(err, ErrCheckpoint) {
+			t.Fatalf("fork: got %v, want %v", err, ErrCheckpoint)
+		}
+	})
+
+	t.Run("import", func(t *testing.T) {
+		var stream bytes.Buffer
+		if _, err := fork.ExportChain(&stream, 0, -1); err != nil {
+			t.Fatal(err)
+		}
+
+		opts := Options{DBFile: filepath.Join(t.TempDir(), "blockchain.db"), Params: checkpointed}
+		bc, _, err := ImportChain(opts, &stream, nil)
+		if err == nil {
+			bc.Close()
+		}
+		if !errors.Is(err, ErrCheckpoint) {
+			t.Fatalf("got %v, want %v", err, ErrCheckpoint)
+		}
+		if dbExists(opts.DBFile) {
+			t.Fatal("the rejected import left a database behind")
+		}
+	})
+
+	t.Run("mine", func(t *testing.T) {
+		w, err := wallet.NewWallet()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fork.params = withCheckpoint(params, 2, make([]byte, 32))
+		defer func() { fork.params = &params }()
+
+		if _, err := fork.MineBlock(nil, string(w.GetAddress())); !errors.Is(err, ErrCheckpoint) {
+			t.Fatalf("got %v, want %v", err, ErrCheckpoint)
+		}
+	})
+}
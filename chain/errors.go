@@ -0,0 +1,18 @@
+package chain
+
+import "errors"
+
+// Errors returned by the chain API.  Callers can test for them with
+// errors.Is; the typed errors in validation.go wrap the relevant sentinel.
+// Transaction and wallet errors are defined in the tx and wallet packages.
+var (
+	ErrChainNotFound     = errors.New("no existing blockchain found, create one first")
+	ErrChainExists       = errors.New("blockchain already exists")
+	ErrOutdatedFormat    = errors.New("the blockchain database uses an outdated format, run migratechain to upgrade it")
+	ErrBlockNotFound     = errors.New("block is not found")
+	ErrBlockPruned       = errors.New("block data has been pruned")
+	ErrDoubleSpend       = errors.New("output is already spent")
+	ErrInsufficientFunds = errors.New("not enough funds")
+	ErrCheckpoint        = errors.New("block conflicts with a checkpoint")
+	ErrTxTooLarge        = errors.New("transaction does not fit in a block")
+)
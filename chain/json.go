@@ -0,0 +1,50 @@
+package chain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/jplesperance/tcn/tx"
+)
+
+// JSON representations use snake_case keys and hex encode byte fields
+
+type blockHeaderJSON struct {
+	Version       int    `json:"version"`
+	PrevBlockHash string `json:"prev_block_hash"`
+	MerkleRoot    string `json:"merkle_root"`
+	Timestamp     int64  `json:"timestamp"`
+	Bits          int    `json:"bits"`
+	Nonce         int    `json:"nonce"`
+	Height        int    `json:"height"`
+}
+
+type blockJSON struct {
+	Hash         string            `json:"hash"`
+	Header       BlockHeader       `json:"header"`
+	Transactions []*tx.Transaction `json:"transactions"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (h BlockHeader) MarshalJSON() ([]byte, error) {
+	return json.Marshal(blockHeaderJSON{
+		Version:       h.Version,
+		PrevBlockHash: hex.EncodeToString(h.PrevBlockHash),
+		MerkleRoot:    hex.EncodeToString(h.MerkleRoot),
+		Timestamp:     h.Timestamp,
+		Bits:          h.Bits,
+		Nonce:         h.Nonce,
+		Height:        h.Height,
+	})
+}
+
+// MarshalJSON implements json.Marshaler.  The header is nested under
+// "header" next to the block hash and transactions.
+func (b Block) MarshalJSON() ([]byte, error) {
+	transactions := b.Transactions
+	if transactions == nil {
+		transactions = []*tx.Transaction{}
+	}
+
+	return json.Marshal(blockJSON{hex.EncodeToString(b.Hash), b.BlockHeader, transactions})
+}
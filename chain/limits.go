@@ -0,0 +1,34 @@
+package chain
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jplesperance/tcn/network"
+)
+
+// CheckBlockLimits rejects blocks exceeding the maximum serialized size or
+// signature operation count of the network
+func CheckBlockLimits(block *Block, params *network.Params) error {
+	if size := len(block.Serialize()); size > params.MaxBlockSize {
+		return fmt.Errorf("block size %d exceeds the maximum of %d bytes", size, params.MaxBlockSize)
+	}
+
+	sigOps := 0
+	for _, tx := range block.Transactions {
+		sigOps += tx.SigOpCount()
+	}
+	if sigOps > params.MaxBlockSigOps {
+		return fmt.Errorf("block requires %d signature operations, exceeding the maximum of %d", sigOps, params.MaxBlockSigOps)
+	}
+
+	return nil
+}
+
+// Return the largest possible size of a block header, used to reserve room
+// for it before the nonce is known
+func maxHeaderSize(header BlockHeader) int {
+	header.Nonce = math.MaxInt64
+
+	return len(header.Serialize())
+}
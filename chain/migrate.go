@@ -0,0 +1,198 @@
+package chain
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/jplesperance/tcn/consensus/pow"
+	"github.com/jplesperance/tcn/tx"
+)
+
+// Layout of blocks written with encoding/gob before the canonical format
+type legacyBlock struct {
+	Timestamp     int64
+	Transactions  []*legacyTransaction
+	PrevBlockHash []byte
+	Hash          []byte
+	Nonce         int
+}
+
+type legacyTransaction struct {
+	ID   []byte
+	Vin  []legacyTXInput
+	Vout []legacyTXOutput
+}
+
+type legacyTXInput struct {
+	Txid      []byte
+	Vout      int
+	Signature []byte
+	PubKey    []byte
+}
+
+type legacyTXOutput struct {
+	Value      int
+	PubKeyHash []byte
+}
+
+// Convert a legacy block into the current representation.
+//
+// gob output depends on process-wide type registration order, so the block
+// hash and transaction IDs cannot be recomputed from the block contents.
+// They are kept verbatim and the block is marked with the legacy version.
+func (lb *legacyBlock) upgrade() *Block {
+	header := BlockHeader{legacyBlockVersion, lb.PrevBlockHash, nil, lb.Timestamp, pow.TargetBits, lb.Nonce, 0}
+	block := &Block{header, lb.Hash, nil}
+
+	for _, ltx := range lb.Transactions {
+		t := &tx.Transaction{Version: tx.LegacyVersion, ID: ltx.ID}
+		for _, in := range ltx.Vin {
+			t.Vin = append(t.Vin, tx.TXInput{Txid: in.Txid, Vout: in.Vout, Signature: in.Signature, PubKey: in.PubKey})
+		}
+		for _, out := range ltx.Vout {
+			t.Vout = append(t.Vout, tx.TXOutput{Value: tx.Amount(out.Value) * tx.Coin, PubKeyHash: out.PubKeyHash})
+		}
+		block.Transactions = append(block.Transactions, t)
+	}
+
+	return block
+}
+
+// Return the legacy genesis block of the main network as migrated from the
+// blockchain.db shipped with TCN
+func legacyGenesisBlock() *Block {
+	lb := legacyBlock{
+		Timestamp: 1522019000,
+		Transactions: []*legacyTransaction{{
+			ID:   mustDecodeHex("da5bb97e281f1c7a7fac69e9ae5af3828a9af614856479d375b38bd806b13196"),
+			Vin:  []legacyTXInput{{Vout: -1, PubKey: []byte(genesisCoinbaseData)}},
+			Vout: []legacyTXOutput{{Value: 10, PubKeyHash: mustDecodeHex("539f3ca16cea8a85c0d8544ead96ead9cd3af7fe767e56")}},
+		}},
+		Hash:  mustDecodeHex("000004980be7bf4f809e75366209d595d9df7b064b06304fa83b5235b11d23b7"),
+		Nonce: 1466534,
+	}
+
+	return lb.upgrade()
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
+
+func decodeGobBlock(data []byte) (*Block, error) {
+	var lb legacyBlock
+	decoder := gob.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&lb); err != nil {
+		return nil, err
+	}
+
+	return lb.upgrade(), nil
+}
+
+// MigrateChain rewrites the blockchain database selected by opts from the
+// legacy gob format into the current one and returns the number of blocks
+// converted.  The blocks are rewritten in the canonical encoding and the UTXO
+// index and undo records are built from their bodies.
+func MigrateChain(opts Options) (int, error) {
+	opts = opts.withDefaults()
+	if dbExists(opts.DBFile) == false {
+		return 0, ErrChainNotFound
+	}
+
+	db, err := bolt.Open(opts.DBFile, 0600, nil)
+	if err != nil {
+		return 0, fmt.Errorf("unable to open database: %v", err)
+	}
+	defer db.Close()
+
+	format, err := dbFormat(db)
+	if err != nil {
+		return 0, err
+	}
+	if format == dbFormatVersion {
+		return 0, nil
+	}
+	if format != 0 {
+		return 0, fmt.Errorf("cannot migrate from database format %d", format)
+	}
+
+	migrated := 0
+	err = db.Update(func(tx *bolt.Tx) error {
+		if err := rewriteBlocks(tx); err != nil {
+			return err
+		}
+
+		var err error
+		migrated, err = reindexUTXO(tx)
+		if err != nil {
+			return err
+		}
+
+		return putDBFormat(tx, dbFormatVersion)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+	log.Info("migrated blockchain", "file", opts.DBFile, "from_format", format, "blocks", migrated)
+
+	return migrated, nil
+}
+
+// Rewrite the gob encoded blocks of a legacy database in the canonical
+// encoding with separate headers
+func rewriteBlocks(tx *bolt.Tx) error {
+	b := tx.Bucket([]byte(blocksBucket))
+	blocks := make(map[string]*Block)
+
+	err := b.ForEach(func(k, v []byte) error {
+		if bytes.Equal(k, []byte("l")) {
+			return nil
+		}
+
+		block, err := decodeGobBlock(v)
+		if err != nil {
+			return fmt.Errorf("failed to decode block %x: %v", k, err)
+		}
+		block.Hash = append([]byte{}, k...)
+		blocks[string(k)] = block
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// heights are assigned walking back from the tip
+	var chain []*Block
+	for hash := b.Get([]byte("l")); len(hash) != 0; {
+		block, ok := blocks[string(hash)]
+		if !ok {
+			return fmt.Errorf("block %x is missing from the database", hash)
+		}
+		chain = append(chain, block)
+		hash = block.PrevBlockHash
+	}
+	for i, block := range chain {
+		block.Height = len(chain) - 1 - i
+	}
+
+	if _, err := tx.CreateBucketIfNotExists([]byte(headersBucket)); err != nil {
+		return err
+	}
+	for _, block := range chain {
+		if err := putBlock(tx, block); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package chain
+
+import (
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/jplesperance/tcn/encoding/canonical"
+)
+
+// Pruning deletes the bodies and undo records of blocks deeper than a
+// configured depth below the tip.  Headers are always kept so the chain can
+// still be walked and its proof of work verified, and the UTXO index stands
+// in for the deleted bodies when validating transactions.  The depth and the
+// progress of pruning are stored in the meta bucket.
+const (
+	pruneDepthKey   = "prunedepth"
+	prunedHeightKey = "prunedheight"
+	prunedBytesKey  = "prunedbytes"
+)
+
+// MinPruneDepth is the smallest number of recent blocks a pruned chain keeps
+const MinPruneDepth = 10
+
+// PruneInfo describes how far a chain has been pruned
+//
+// Depth: number of recent blocks whose bodies are kept, 0 if pruning is off
+// PrunedHeight: every block below this height has had its body deleted
+// FreedBytes: total size of the deleted block bodies and undo records.
+// The space is freed inside the database for new blocks to reuse; the
+// database file does not shrink.
+type PruneInfo struct {
+	Depth        int
+	PrunedHeight int
+	FreedBytes   int64
+}
+
+// Read an integer from the meta bucket, 0 if it has never been set
+func getMetaUint(btx *bolt.Tx, key string) (uint64, error) {
+	b := btx.Bucket([]byte(metaBucket))
+	if b == nil {
+		return 0, nil
+	}
+	value := b.Get([]byte(key))
+	if value == nil {
+		return 0, nil
+	}
+
+	d := canonical.NewDecoder(value)
+	v := d.ReadUvarint()
+	if err := d.Finish(); err != nil {
+		return 0, fmt.Errorf("failed to decode %s: %v", key, err)
+	}
+
+	return v, nil
+}
+
+func putMetaUint(btx *bolt.Tx, key string, v uint64) error {
+	b, err := btx.CreateBucketIfNotExists([]byte(metaBucket))
+	if err != nil {
+		return err
+	}
+
+	var e canonical.Encoder
+	e.WriteUvarint(v)
+
+	return b.Put([]byte(key), e.Bytes())
+}
+
+func getPruneInfo(btx *bolt.Tx) (PruneInfo, error) {
+	var values [3]uint64
+	for i, key := range []string{pruneDepthKey, prunedHeightKey, prunedBytesKey} {
+		v, err := getMetaUint(btx, key)
+		if err != nil {
+			return PruneInfo{}, err
+		}
+		values[i] = v
+	}
+
+	return PruneInfo{int(values[0]), int(values[1]), int64(values[2])}, nil
+}
+
+// PruneInfo returns the pruning state of the chain
+func (bc *Blockchain) PruneInfo() (PruneInfo, error) {
+	var info PruneInfo
+
+	err := bc.db.View(func(btx *bolt.Tx) error {
+		var err error
+		info, err = getPruneInfo(btx)
+		return err
+	})
+
+	return info, err
+}
+
+// SetPruneDepth stores the number of recent blocks whose bodies are kept,
+// or 0 to stop pruning, and prunes the chain down to it.  The setting
+// applies to every block added afterwards.  Pruned bodies are never
+// restored.
+func (bc *Blockchain) SetPruneDepth(depth int) error {
+	if depth != 0 && depth < MinPruneDepth {
+		return fmt.Errorf("prune depth must be 0 or at least %d blocks", MinPruneDepth)
+	}
+
+	return bc.db.Update(func(btx *bolt.Tx) error {
+		if err := putMetaUint(btx, pruneDepthKey, uint64(depth)); err != nil {
+			return err
+		}
+
+		return prune(btx, bc.tip)
+	})
+}
+
+// Delete the bodies and undo records of the blocks more than the prune depth
+// below tip
+func prune(btx *bolt.Tx, tip []byte) error {
+	info, err := getPruneInfo(btx)
+	if err != nil || info.Depth == 0 {
+		return err
+	}
+
+	b := btx.Bucket([]byte(blocksBucket))
+	undo := btx.Bucket([]byte(undoBucket))
+	prunedHeight := info.PrunedHeight
+	pruned := 0
+	var freed int64
+
+	for hash, depth := tip, 0; len(hash) != 0; depth++ {
+		header, err := getHeader(btx, hash)
+		if err != nil {
+			return err
+		}
+		if header.Height < info.PrunedHeight {
+			break
+		}
+
+		if depth >= info.Depth {
+			if body := b.Get(hash); body != nil {
+				freed += int64(len(body))
+				pruned++
+				if err := b.Delete(hash); err != nil {
+					return err
+				}
+			}
+			if record := undo.Get(hash); record != nil {
+				freed += int64(len(record))
+				if err := undo.Delete(hash); err != nil {
+					return err
+				}
+			}
+			if header.Height >= prunedHeight {
+				prunedHeight = header.Height + 1
+			}
+		}
+
+		hash = header.PrevBlockHash
+	}
+
+	if pruned == 0 {
+		return nil
+	}
+	if err := putMetaUint(btx, prunedHeightKey, uint64(prunedHeight)); err != nil {
+		return err
+	}
+	if err := putMetaUint(btx, prunedBytesKey, uint64(info.FreedBytes+freed)); err != nil {
+		return err
+	}
+	log.Info("pruned blocks", "blocks", pruned, "bytes", freed, "pruned_height", prunedHeight)
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package chain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/jplesperance/tcn/tx"
+	"github.com/jplesperance/tcn/wallet"
+)
+
+// Store a prune depth without the MinPruneDepth check, so that a short test
+// chain can be pruned, and prune down to it
+func setTestPruneDepth(t *testing.T, bc *Blockchain, depth int) {
+	t.Helper()
+
+	err := bc.db.Update(func(btx *bolt.Tx) error {
+		if err := putMetaUint(btx, pruneDepthKey, uint64(depth)); err != nil {
+			return err
+		}
+
+		return prune(btx, bc.tip)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Return the hashes of the chain's blocks by height
+func chainHashes(t *testing.T, bc *Blockchain) [][]byte {
+	t.Helper()
+
+	var hashes [][]byte
+	for hash := bc.Tip(); len(hash) != 0; {
+		header, err := bc.GetHeader(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append([][]byte{hash}, hashes...)
+		hash = header.PrevBlockHash
+	}
+
+	return hashes
+}
+
+// Check that the blocks below prunedHeight have no body and the others do
+func checkPruned(t *testing.T, bc *Blockchain, prunedHeight int) {
+	t.Helper()
+
+	for height, hash := range chainHashes(t, bc) {
+		_, err := bc.GetBlock(hash)
+		if height < prunedHeight && !errors.Is(err, ErrBlockPruned) {
+			t.Fatalf("block at height %d: got %v, want %v", height, err, ErrBlockPruned)
+		}
+		if height >= prunedHeight && err != nil {
+			t.Fatalf("block at height %d: %v", height, err)
+		}
+	}
+
+	info, err := bc.PruneInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.PrunedHeight != prunedHeight || info.FreedBytes <= 0 {
+		t.Fatalf("pruned height %d, freed %d bytes, want height %d", info.PrunedHeight, info.FreedBytes, prunedHeight)
+	}
+}
+
+func TestSetPruneDepth(t *testing.T) {
+	w, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc, _ := createFundedChain(t, w)
+
+	if err := bc.SetPruneDepth(MinPruneDepth - 1); err == nil {
+		t.Fatalf("depth %d accepted", MinPruneDepth-1)
+	}
+	if err := bc.SetPruneDepth(MinPruneDepth); err != nil {
+		t.Fatal(err)
+	}
+	info, err := bc.PruneInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Depth != MinPruneDepth || info.PrunedHeight != 0 {
+		t.Fatalf("got %+v, want depth %d and nothing pruned", info, MinPruneDepth)
+	}
+	if err := bc.SetPruneDepth(0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	w, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := string(w.GetAddress())
+	bc, coinbase := createFundedChain(t, w)
+	funds := []*tx.Transaction{coinbase}
+	reward := Outpoint{coinbase.ID, 0}
+
+	for i := 0; i < 2; i++ {
+		if _, err := bc.MineBlock(nil, address); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setTestPruneDepth(t, bc, 2)
+	checkPruned(t, bc, 2)
+
+	t.Run("headers are kept", func(t *testing.T) {
+		count, err := bc.ValidateHeaders()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 4 {
+			t.Fatalf("validated %d headers, want 4", count)
+		}
+	})
+
+	spend := signedSpend(t, w, funds, []Outpoint{reward}, 10*tx.Coin)
+	other := signedSpend(t, w, funds, []Outpoint{reward}, 9*tx.Coin)
+
+	t.Run("output of a pruned block", func(t *testing.T) {
+		if err := bc.ValidateTransactions([]*tx.Transaction{spend}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := bc.MineBlock([]*tx.Transaction{spend}, address); err != nil {
+			t.Fatal(err)
+		}
+		checkPruned(t, bc, 3)
+	})
+
+	t.Run("output spent in a pruned block", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if _, err := bc.MineBlock(nil, address); err != nil {
+				t.Fatal(err)
+			}
+		}
+		checkPruned(t, bc, 5)
+
+		// the spender's body is gone, so only the output's absence is known
+		var missingErr *MissingOutputError
+		if err := bc.ValidateTransactions([]*tx.Transaction{other}); !errors.As(err, &missingErr) {
+			t.Fatalf("got %v, want a *MissingOutputError", err)
+		}
+	})
+}
@@ -0,0 +1,277 @@
+package chain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// Blocks marked invalid by InvalidateBlock are kept in the invalid bucket,
+// keyed by block hash, with the hash of the tip of the chain they were
+// disconnected from so that ReconsiderBlock can connect that chain again.
+// Disconnected blocks stay in the database.
+const invalidBucket = "invalid"
+
+// InvalidateBlock marks the block with the given hash invalid and
+// disconnects it and all its descendants from the active chain, restoring
+// the outputs they spent, and returns the number of blocks disconnected.
+// Blocks at or below the last checkpoint cannot be invalidated.
+func (bc *Blockchain) InvalidateBlock(hash []byte) (int, error) {
+	header, err := bc.activeHeader(hash)
+	if err != nil {
+		return 0, err
+	}
+	if header.Height == 0 {
+		return 0, errors.New("the genesis block cannot be invalidated")
+	}
+	if last := bc.params.LastCheckpoint(); last != nil && header.Height <= last.Height {
+		return 0, fmt.Errorf("%w: block %x at height %d is at or below the checkpoint at height %d", ErrCheckpoint, hash, header.Height, last.Height)
+	}
+
+	disconnected := 0
+	err = bc.db.Update(func(tx *bolt.Tx) error {
+		for tip := bc.tip; !bytes.Equal(tip, header.PrevBlockHash); disconnected++ {
+			block, err := disconnectTip(tx, tip)
+			if err != nil {
+				return err
+			}
+			tip = block.PrevBlockHash
+		}
+
+		b, err := tx.CreateBucketIfNotExists([]byte(invalidBucket))
+		if err != nil {
+			return err
+		}
+
+		return b.Put(hash, bc.tip)
+	})
+	if err != nil {
+		return 0, err
+	}
+	bc.tip = header.PrevBlockHash
+	log.Info("invalidated block", "hash", hex.EncodeToString(hash), "height", header.Height, "disconnected", disconnected)
+
+	return disconnected, nil
+}
+
+// reconsideration is the outcome of reconsidering a block
+//
+// tip: the tip of the chain once the block is reconsidered
+// abandoned: number of blocks of the active chain disconnected
+// connected: number of blocks of the block's chain connected
+// invalid: hash of the block of the block's chain that failed validation,
+// nil if none did
+type reconsideration struct {
+	tip       []byte
+	abandoned int
+	connected int
+	invalid   []byte
+}
+
+// ReconsiderBlock removes the invalid mark set by InvalidateBlock and, if
+// the chain the block was disconnected from is longer than the active
+// chain, makes it active again, validating every block connected.  Blocks
+// of the active chain above the fork are abandoned.  It returns the number
+// of blocks connected.
+//
+// The chain is switched in a single database transaction, so a block
+// failing validation leaves the active chain untouched; the failing block
+// is then marked invalid in place of the reconsidered one.
+func (bc *Blockchain) ReconsiderBlock(hash []byte) (int, error) {
+	var r reconsideration
+	err := bc.db.Update(func(btx *bolt.Tx) error {
+		return bc.reconsider(btx, hash, &r)
+	})
+	if err != nil && r.invalid != nil {
+		if markErr := bc.db.Update(func(btx *bolt.Tx) error { return moveInvalidMark(btx, hash, r.invalid) }); markErr != nil {
+			return 0, fmt.Errorf("%v, and block %x could not be marked invalid: %v", err, r.invalid, markErr)
+		}
+		log.Warn("block is invalid, kept the active chain", "hash", hex.EncodeToString(r.invalid), "tip", hex.EncodeToString(bc.tip))
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if r.connected == 0 {
+		log.Info("reconsidered block, its chain is not longer than the active chain", "hash", hex.EncodeToString(hash))
+		return 0, nil
+	}
+	bc.tip = r.tip
+	log.Info("reconsidered block", "hash", hex.EncodeToString(hash), "disconnected", r.abandoned, "connected", r.connected)
+
+	return r.connected, nil
+}
+
+// Remove the invalid mark of hash in btx and, if its chain is longer than
+// the active chain, switch to it, recording the outcome in r
+func (bc *Blockchain) reconsider(btx *bolt.Tx, hash []byte, r *reconsideration) error {
+	marks := btx.Bucket([]byte(invalidBucket))
+	var branchTip []byte
+	if marks != nil {
+		branchTip = append([]byte{}, marks.Get(hash)...)
+	}
+	if len(branchTip) == 0 {
+		return fmt.Errorf("block %x is not marked invalid", hash)
+	}
+
+	branch, fork, err := findFork(btx, branchTip)
+	if err != nil {
+		return err
+	}
+	for _, other := range branch {
+		if !bytes.Equal(other, hash) && marks.Get(other) != nil {
+			return fmt.Errorf("block %x is also marked invalid, reconsider it first", other)
+		}
+	}
+	if err := marks.Delete(hash); err != nil {
+		return err
+	}
+
+	tip, err := getHeader(btx, bc.tip)
+	if err != nil {
+		return err
+	}
+	forkHeader, err := getHeader(btx, fork)
+	if err != nil {
+		return err
+	}
+	if forkHeader.Height+len(branch) <= tip.Height {
+		return nil
+	}
+
+	// disconnect the active chain down to the fork
+	for tip := bc.tip; !bytes.Equal(tip, fork); r.abandoned++ {
+		block, err := disconnectTip(btx, tip)
+		if err != nil {
+			return err
+		}
+		tip = block.PrevBlockHash
+	}
+
+	for i := len(branch) - 1; i >= 0; i-- {
+		if err := bc.connectBranchBlock(btx, branch[i]); err != nil {
+			r.invalid = branch[i]
+			return err
+		}
+		r.connected++
+	}
+	r.tip = branchTip
+
+	return prune(btx, branchTip)
+}
+
+// Validate a stored block extending the tip of btx and connect it
+func (bc *Blockchain) connectBranchBlock(btx *bolt.Tx, hash []byte) error {
+	block, err := getBlock(btx, hash)
+	if err != nil {
+		return err
+	}
+	prev, err := getHeader(btx, block.PrevBlockHash)
+	if err != nil {
+		return err
+	}
+	mtp, err := medianTimePast(btx, block.PrevBlockHash)
+	if err != nil {
+		return err
+	}
+	if err := bc.validateBlock(btx, block, prev, block.PrevBlockHash, mtp, time.Now(), true); err != nil {
+		return err
+	}
+
+	return connectTip(btx, block)
+}
+
+// Replace the invalid mark of reconsidered with one of invalid, the block
+// of its chain that failed validation, keeping the tip of that chain
+func moveInvalidMark(btx *bolt.Tx, reconsidered, invalid []byte) error {
+	marks := btx.Bucket([]byte(invalidBucket))
+	branchTip := append([]byte{}, marks.Get(reconsidered)...)
+	if err := marks.Delete(reconsidered); err != nil {
+		return err
+	}
+
+	return marks.Put(invalid, branchTip)
+}
+
+// Disconnect the tip block, whose hash is given, and make its parent the
+// tip
+func disconnectTip(tx *bolt.Tx, hash []byte) (*Block, error) {
+	block, err := getBlock(tx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if err := disconnectBlock(tx, block); err != nil {
+		return nil, err
+	}
+	if err := tx.Bucket([]byte(blocksBucket)).Put([]byte("l"), block.PrevBlockHash); err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// Return the header of the block with the given hash, which must be in the
+// active chain
+func (bc *Blockchain) activeHeader(hash []byte) (*BlockHeader, error) {
+	hi := bc.HeaderIterator()
+	for {
+		header, current, err := hi.Next()
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			break
+		}
+		if bytes.Equal(current, hash) {
+			return header, nil
+		}
+	}
+
+	if _, err := bc.GetHeader(hash); err == nil {
+		return nil, fmt.Errorf("block %x is not in the active chain", hash)
+	}
+	return nil, fmt.Errorf("%w: %x", ErrBlockNotFound, hash)
+}
+
+// Walk back from branchTip to the last block it shares with the active
+// chain of btx, returning the hashes of the blocks above that fork,
+// branchTip first, and the hash of the fork
+func findFork(btx *bolt.Tx, branchTip []byte) ([][]byte, []byte, error) {
+	active := make(map[string]bool)
+	for hash := btx.Bucket([]byte(blocksBucket)).Get([]byte("l")); len(hash) != 0; {
+		header, err := getHeader(btx, hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		active[string(hash)] = true
+		hash = header.PrevBlockHash
+	}
+
+	var branch [][]byte
+	for hash := branchTip; !active[string(hash)]; {
+		if len(hash) == 0 {
+			return nil, nil, errors.New("the block does not share a genesis block with the active chain")
+		}
+		header, err := getHeader(btx, hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		branch = append(branch, hash)
+		hash = header.PrevBlockHash
+	}
+
+	fork := branchTip
+	if len(branch) > 0 {
+		last, err := getHeader(btx, branch[len(branch)-1])
+		if err != nil {
+			return nil, nil, err
+		}
+		fork = last.PrevBlockHash
+	}
+
+	return branch, fork, nil
+}
@@ -0,0 +1,183 @@
+package chain
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/jplesperance/tcn/network"
+	"github.com/jplesperance/tcn/tx"
+	"github.com/jplesperance/tcn/wallet"
+)
+
+// Return the undo record of the block with the given hash
+func undoRecord(t *testing.T, bc *Blockchain, hash []byte) ([]tx.UTXO, error) {
+	t.Helper()
+
+	var spent []tx.UTXO
+	err := bc.db.View(func(btx *bolt.Tx) error {
+		var err error
+		spent, err = getUndo(btx, hash)
+		return err
+	})
+
+	return spent, err
+}
+
+// Check whether the output at index of txID is unspent
+func checkUnspent(t *testing.T, bc *Blockchain, txID []byte, index int, want bool) {
+	t.Helper()
+
+	utxo, err := bc.GetUTXO(txID, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (utxo != nil) != want {
+		t.Fatalf("output %x:%d unspent: %t, want %t", txID, index, utxo != nil, want)
+	}
+}
+
+// Mine a block of candidates on bc paying address
+func mine(t *testing.T, bc *Blockchain, candidates []*tx.Transaction, address string) *Block {
+	t.Helper()
+
+	block, err := bc.MineBlock(candidates, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return block
+}
+
+func TestUndoRecord(t *testing.T) {
+	w, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc, coinbase := createFundedChain(t, w)
+	spend := signedSpend(t, w, []*tx.Transaction{coinbase}, []Outpoint{{coinbase.ID, 0}}, 10*tx.Coin)
+	block := mine(t, bc, []*tx.Transaction{spend}, string(w.GetAddress()))
+
+	spent, err := undoRecord(t, bc, block.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tx.UTXO{{TxID: coinbase.ID, Index: 0, Output: coinbase.Vout[0], Height: 1}}
+	if !reflect.DeepEqual(spent, want) {
+		t.Fatalf("undo record %+v, want %+v", spent, want)
+	}
+
+	if _, err := bc.InvalidateBlock(block.Hash); err != nil {
+		t.Fatal(err)
+	}
+	checkUnspent(t, bc, coinbase.ID, 0, true)
+	checkUnspent(t, bc, spend.ID, 0, false)
+	if _, err := undoRecord(t, bc, block.Hash); !errors.Is(err, ErrBlockPruned) {
+		t.Fatalf("undo record of a disconnected block: got %v, want %v", err, ErrBlockPruned)
+	}
+}
+
+func TestInvalidateBlock(t *testing.T) {
+	w, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc, _ := createFundedChain(t, w)
+	genesis := NewGenesisBlock(&network.Test)
+
+	if _, err := bc.InvalidateBlock(genesis.Hash); err == nil {
+		t.Fatal("the genesis block was invalidated")
+	}
+	if _, err := bc.InvalidateBlock(make([]byte, 32)); !errors.Is(err, ErrBlockNotFound) {
+		t.Fatalf("unknown block: got %v, want %v", err, ErrBlockNotFound)
+	}
+
+	tip := bc.Tip()
+	bc.params = withCheckpoint(network.Test, 1, tip)
+	defer func() { bc.params = &network.Test }()
+	if _, err := bc.InvalidateBlock(tip); !errors.Is(err, ErrCheckpoint) {
+		t.Fatalf("checkpointed block: got %v, want %v", err, ErrCheckpoint)
+	}
+}
+
+func TestReconsiderBlock(t *testing.T) {
+	w, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := string(w.GetAddress())
+	bc, coinbase := createFundedChain(t, w)
+	spend := signedSpend(t, w, []*tx.Transaction{coinbase}, []Outpoint{{coinbase.ID, 0}}, 10*tx.Coin)
+	block2 := mine(t, bc, []*tx.Transaction{spend}, address)
+	block3 := mine(t, bc, nil, address)
+
+	disconnected, err := bc.InvalidateBlock(block2.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if disconnected != 2 || !bytes.Equal(bc.Tip(), block2.PrevBlockHash) {
+		t.Fatalf("disconnected %d blocks to tip %x, want 2 to %x", disconnected, bc.Tip(), block2.PrevBlockHash)
+	}
+	checkUnspent(t, bc, coinbase.ID, 0, true)
+	if _, err := bc.InvalidateBlock(block3.Hash); err == nil {
+		t.Fatal("a block outside the active chain was invalidated")
+	}
+
+	// the active chain is as long as the invalidated one once this is mined,
+	// paying another address so that its coinbase differs from block2's
+	minerWallet, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := mine(t, bc, nil, string(minerWallet.GetAddress()))
+
+	t.Run("invalid block", func(t *testing.T) {
+		bc.params = withCheckpoint(network.Test, 3, make([]byte, 32))
+		defer func() { bc.params = &network.Test }()
+
+		if _, err := bc.ReconsiderBlock(block2.Hash); !errors.Is(err, ErrCheckpoint) {
+			t.Fatalf("got %v, want %v", err, ErrCheckpoint)
+		}
+		if !bytes.Equal(bc.Tip(), other.Hash) {
+			t.Fatalf("tip %x, want the active tip %x", bc.Tip(), other.Hash)
+		}
+		checkUnspent(t, bc, coinbase.ID, 0, true)
+		checkUnspent(t, bc, spend.ID, 0, false)
+		if _, err := bc.ReconsiderBlock(block2.Hash); err == nil {
+			t.Fatal("the mark was not moved to the invalid block")
+		}
+	})
+
+	t.Run("longer chain", func(t *testing.T) {
+		connected, err := bc.ReconsiderBlock(block3.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if connected != 2 || !bytes.Equal(bc.Tip(), block3.Hash) {
+			t.Fatalf("connected %d blocks to tip %x, want 2 to %x", connected, bc.Tip(), block3.Hash)
+		}
+		checkUnspent(t, bc, coinbase.ID, 0, false)
+		checkUnspent(t, bc, spend.ID, 0, true)
+		checkUnspent(t, bc, other.Transactions[0].ID, 0, false)
+	})
+
+	t.Run("chain not longer", func(t *testing.T) {
+		if _, err := bc.InvalidateBlock(block3.Hash); err != nil {
+			t.Fatal(err)
+		}
+		tip := mine(t, bc, nil, address)
+
+		connected, err := bc.ReconsiderBlock(block3.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if connected != 0 || !bytes.Equal(bc.Tip(), tip.Hash) {
+			t.Fatalf("connected %d blocks to tip %x, want 0 to %x", connected, bc.Tip(), tip.Hash)
+		}
+		if _, err := bc.ReconsiderBlock(block3.Hash); err == nil {
+			t.Fatal("the mark was not removed")
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package chain
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/jplesperance/tcn/tx"
+	"github.com/jplesperance/tcn/wallet"
+)
+
+// NewUTXOTransaction creates a transaction sending amount to the to address
+//
+// Inputs are drawn from the from address, or from every address in wallets
+// when from is empty, using the given coin selection strategy.  Watch-only
+// addresses cannot sign, so their outputs are never selected.  Any change
+// is sent to a freshly generated wallet address, which is only added to
+// wallets in memory: the caller saves the wallet file before the
+// transaction is mined.
+func NewUTXOTransaction(wallets *wallet.Wallets, from, to string, amount tx.Amount, selector tx.CoinSelector, bc *Blockchain) (*tx.Transaction, error) {
+	var inputs []tx.TXInput
+	var outputs []tx.TXOutput
+
+	if err := amount.Validate(); err != nil {
+		return nil, err
+	}
+	if amount == 0 {
+		return nil, tx.ErrInvalidAmount
+	}
+	if !wallet.ValidateAddress(to) {
+		return nil, fmt.Errorf("%w: %s", wallet.ErrInvalidAddress, to)
+	}
+
+	addresses := wallets.GetAddresses()
+	if from != "" {
+		if _, err := wallets.GetWallet(from); err != nil {
+			return nil, err
+		}
+		addresses = []string{from}
+	}
+
+	// wallets owning the candidate outputs, keyed by public key hash
+	owners := make(map[string]*wallet.Wallet)
+	var pubKeyHashes [][]byte
+	for _, address := range addresses {
+		w := wallets.Wallets[address]
+		pubKeyHash := wallet.HashPubKey(w.PublicKey)
+		owners[hex.EncodeToString(pubKeyHash)] = w
+		pubKeyHashes = append(pubKeyHashes, pubKeyHash)
+	}
+
+	acc, selected, err := bc.FindSpendableOutputs(pubKeyHashes, amount, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	if acc < amount {
+		return nil, fmt.Errorf("%w: have %s, need %s", ErrInsufficientFunds, acc, amount)
+	}
+
+	signers := make(map[string]*wallet.Wallet)
+	for _, utxo := range selected {
+		owner := hex.EncodeToString(utxo.Output.PubKeyHash)
+		w := owners[owner]
+		signers[owner] = w
+
+		input := tx.TXInput{Txid: utxo.TxID, Vout: utxo.Index, PubKey: w.PublicKey}
+		inputs = append(inputs, input)
+	}
+
+	changeValue, err := acc.Sub(amount)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := tx.NewTXOutput(amount, to)
+	if err != nil {
+		return nil, err
+	}
+	outputs = append(outputs, *output)
+	if changeValue > 0 {
+		change, err := wallets.CreateWallet()
+		if err != nil {
+			return nil, err
+		}
+		output, err := tx.NewTXOutput(changeValue, change)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, *output)
+	}
+
+	newTx := tx.Transaction{Version: tx.CurrentVersion, Vin: inputs, Vout: outputs}
+
+	newTx.ID = newTx.ComputeID()
+	for _, w := range signers {
+		if err := bc.SignTransaction(&newTx, w.PrivateKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return &newTx, nil
+}
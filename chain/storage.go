@@ -0,0 +1,210 @@
+package chain
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/jplesperance/tcn/network"
+)
+
+// Blocks are stored split in two buckets keyed by block hash: the headers
+// bucket holds each serialized BlockHeader and the blocks bucket holds the
+// serialized transactions.  Headers can therefore be loaded and validated
+// without decoding block bodies.
+const headersBucket = "headers"
+
+// Write a block's header and body to the database
+func putBlock(tx *bolt.Tx, block *Block) error {
+	err := tx.Bucket([]byte(headersBucket)).Put(block.Hash, block.BlockHeader.Serialize())
+	if err != nil {
+		return err
+	}
+
+	return tx.Bucket([]byte(blocksBucket)).Put(block.Hash, SerializeTransactions(block.Transactions))
+}
+
+// Read the header stored under hash
+func getHeader(tx *bolt.Tx, hash []byte) (*BlockHeader, error) {
+	encoded := tx.Bucket([]byte(headersBucket)).Get(hash)
+	if encoded == nil {
+		return nil, fmt.Errorf("block header %x is not found", hash)
+	}
+
+	return DeserializeBlockHeader(encoded)
+}
+
+// Read the full block stored under hash
+//
+// The hash is taken from the key rather than recomputed so that blocks
+// hashed with older schemes keep their identity.
+func getBlock(tx *bolt.Tx, hash []byte) (*Block, error) {
+	header, err := getHeader(tx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := tx.Bucket([]byte(blocksBucket)).Get(hash)
+	if encoded == nil {
+		// the header outlives the body when the block is pruned
+		return nil, fmt.Errorf("%w: block %x at height %d", ErrBlockPruned, hash, header.Height)
+	}
+
+	transactions, err := DeserializeTransactions(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	block := &Block{*header, append([]byte{}, hash...), transactions}
+
+	return block, nil
+}
+
+// GetHeader returns the header of the block with the given hash
+func (bc *Blockchain) GetHeader(hash []byte) (*BlockHeader, error) {
+	var header *BlockHeader
+
+	err := bc.db.View(func(tx *bolt.Tx) error {
+		var err error
+		header, err = getHeader(tx, hash)
+		return err
+	})
+
+	return header, err
+}
+
+// GetBlock returns the block with the given hash
+func (bc *Blockchain) GetBlock(hash []byte) (*Block, error) {
+	var block *Block
+
+	err := bc.db.View(func(tx *bolt.Tx) error {
+		var err error
+		block, err = getBlock(tx, hash)
+		return err
+	})
+
+	return block, err
+}
+
+// HeaderIterator walks the chain's headers from the tip back to genesis
+// without loading block bodies
+type HeaderIterator struct {
+	currentHash []byte
+	db          *bolt.DB
+}
+
+func (bc *Blockchain) HeaderIterator() *HeaderIterator {
+	return &HeaderIterator{bc.tip, bc.db}
+}
+
+// Return the next header and its hash, or a nil header once genesis has
+// been passed
+func (i *HeaderIterator) Next() (*BlockHeader, []byte, error) {
+	if len(i.currentHash) == 0 {
+		return nil, nil, nil
+	}
+
+	var header *BlockHeader
+	hash := i.currentHash
+
+	err := i.db.View(func(tx *bolt.Tx) error {
+		var err error
+		header, err = getHeader(tx, hash)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	i.currentHash = header.PrevBlockHash
+
+	return header, hash, nil
+}
+
+// ValidateHeader checks a header's proof of work, that it extends prev, the
+// header of its parent or nil for genesis, and that it matches the
+// checkpoint of the network at its height.  Headers older than
+// blockVersion, whose hash cannot be recomputed, are trusted as stored, but
+// only at or below the last checkpoint of the network, which pins their
+// chain, and never after a header of blockVersion.
+func ValidateHeader(header *BlockHeader, hash []byte, prev *BlockHeader, prevHash []byte, params *network.Params) error {
+	if prev == nil {
+		if len(header.PrevBlockHash) != 0 || header.Height != 0 {
+			return fmt.Errorf("block %x is not a valid genesis block", hash)
+		}
+	} else {
+		if !bytes.Equal(header.PrevBlockHash, prevHash) {
+			return fmt.Errorf("block %x does not extend block %x", hash, prevHash)
+		}
+		if header.Height != prev.Height+1 {
+			return fmt.Errorf("block %x has height %d, expected %d", hash, header.Height, prev.Height+1)
+		}
+	}
+	if err := checkCheckpoint(params, header.Height, hash); err != nil {
+		return err
+	}
+
+	if header.Version < blockVersion {
+		if prev != nil && prev.Version >= blockVersion {
+			return fmt.Errorf("block %x has legacy version %d after a version %d block", hash, header.Version, prev.Version)
+		}
+		if last := params.LastCheckpoint(); last == nil || header.Height > last.Height {
+			return fmt.Errorf("block %x has legacy version %d above the last checkpoint", hash, header.Version)
+		}
+		return nil
+	}
+
+	if !bytes.Equal(header.Hash(), hash) {
+		return fmt.Errorf("block %x does not match its header hash", hash)
+	}
+	if !header.CheckProofOfWork() {
+		return fmt.Errorf("block %x has invalid proof of work", hash)
+	}
+
+	return nil
+}
+
+// ValidateHeaders checks every header from genesis up to the tip, including
+// the timestamp rules, returning the number of headers checked
+func (bc *Blockchain) ValidateHeaders() (int, error) {
+	var headers []*BlockHeader
+	var hashes [][]byte
+
+	hi := bc.HeaderIterator()
+	for {
+		header, hash, err := hi.Next()
+		if err != nil {
+			return 0, err
+		}
+		if header == nil {
+			break
+		}
+		headers = append(headers, header)
+		hashes = append(hashes, hash)
+	}
+
+	var timestamps []int64
+	now := time.Now()
+
+	for i := len(headers) - 1; i >= 0; i-- {
+		var prev *BlockHeader
+		var prevHash []byte
+		if i+1 < len(headers) {
+			prev, prevHash = headers[i+1], hashes[i+1]
+		}
+		if err := ValidateHeader(headers[i], hashes[i], prev, prevHash, bc.params); err != nil {
+			return 0, err
+		}
+		if err := CheckBlockTime(headers[i], medianTime(timestamps), now, bc.params); err != nil {
+			return 0, fmt.Errorf("block %x: %v", hashes[i], err)
+		}
+
+		timestamps = append(timestamps, headers[i].Timestamp)
+		if len(timestamps) > medianTimeSpan {
+			timestamps = timestamps[1:]
+		}
+	}
+
+	return len(headers), nil
+}
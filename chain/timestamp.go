@@ -0,0 +1,80 @@
+package chain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/jplesperance/tcn/network"
+)
+
+// number of blocks whose median timestamp a new block must exceed
+const medianTimeSpan = 11
+
+// MedianTimePast returns the median timestamp of the block with the given
+// hash and up to medianTimeSpan-1 of its ancestors.  A block extending this
+// one must have a later timestamp, and it is the time against which
+// locktimes are evaluated.
+func (bc *Blockchain) MedianTimePast(hash []byte) (int64, error) {
+	var mtp int64
+
+	err := bc.db.View(func(tx *bolt.Tx) error {
+		var err error
+		mtp, err = medianTimePast(tx, hash)
+		return err
+	})
+
+	return mtp, err
+}
+
+func medianTimePast(tx *bolt.Tx, hash []byte) (int64, error) {
+	var timestamps []int64
+
+	for len(hash) != 0 && len(timestamps) < medianTimeSpan {
+		header, err := getHeader(tx, hash)
+		if err != nil {
+			return 0, err
+		}
+		timestamps = append(timestamps, header.Timestamp)
+		hash = header.PrevBlockHash
+	}
+
+	return medianTime(timestamps), nil
+}
+
+// Return the median of the given timestamps, 0 if there are none
+func medianTime(timestamps []int64) int64 {
+	if len(timestamps) == 0 {
+		return 0
+	}
+
+	sorted := make([]int64, len(timestamps))
+	copy(sorted, timestamps)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return sorted[len(sorted)/2]
+}
+
+// CheckBlockTime enforces the timestamp consensus rules: a block must be
+// later than the median time past of its parent and no further than the
+// MaxFutureBlockTime of the network ahead of now.  Headers older than
+// blockVersion are trusted as stored.
+func CheckBlockTime(header *BlockHeader, medianTimePast int64, now time.Time, params *network.Params) error {
+	if header.Version < blockVersion {
+		return nil
+	}
+
+	if header.Height > 0 && header.Timestamp <= medianTimePast {
+		return fmt.Errorf("block timestamp %d is not after the median time past %d",
+			header.Timestamp, medianTimePast)
+	}
+
+	limit := now.Add(params.MaxFutureBlockTime).Unix()
+	if header.Timestamp > limit {
+		return fmt.Errorf("block timestamp %d is more than %v ahead of node time",
+			header.Timestamp, params.MaxFutureBlockTime)
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package chain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jplesperance/tcn/network"
+)
+
+func TestCheckBlockTime(t *testing.T) {
+	now := time.Unix(1760900000, 0)
+	params := network.Test
+	params.MaxFutureBlockTime = time.Minute
+
+	tests := []struct {
+		name      string
+		header    BlockHeader
+		mtp       int64
+		wantError bool
+	}{
+		{"after the median time past", BlockHeader{Version: blockVersion, Height: 5, Timestamp: 1000}, 999, false},
+		{"at the median time past", BlockHeader{Version: blockVersion, Height: 5, Timestamp: 1000}, 1000, true},
+		{"genesis ignores the median time past", BlockHeader{Version: blockVersion, Timestamp: 1000}, 1000, false},
+		{"at the future limit", BlockHeader{Version: blockVersion, Height: 5, Timestamp: now.Unix() + 60}, 0, false},
+		{"beyond the future limit", BlockHeader{Version: blockVersion, Height: 5, Timestamp: now.Unix() + 61}, 0, true},
+		{"legacy header trusted", BlockHeader{Version: legacyBlockVersion, Height: 5, Timestamp: 0}, 1000, false},
+	}
+
+	for _, tt := range tests {
+		err := CheckBlockTime(&tt.header, tt.mtp, now, &params)
+		if (err != nil) != tt.wantError {
+			t.Errorf("%s: got %v, want error %v", tt.name, err, tt.wantError)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package chain
+
+import (
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/jplesperance/tcn/encoding/canonical"
+	"github.com/jplesperance/tcn/tx"
+)
+
+// Undo records, keyed by block hash, list the outputs each block of the
+// active chain spent so that the block can be disconnected from the UTXO
+// index.  A record is a list of the spent entries, in the order the block's
+// inputs spend them, each as the bytes of its UTXO index key followed by the
+// bytes of its value.
+const undoBucket = "undo"
+
+func putUndo(btx *bolt.Tx, hash []byte, spent []tx.UTXO) error {
+	var e canonical.Encoder
+	e.WriteUvarint(uint64(len(spent)))
+	for _, utxo := range spent {
+		e.WriteBytes(utxoKey(utxo.TxID, utxo.Index))
+		e.WriteBytes(encodeUTXO(utxo))
+	}
+
+	return btx.Bucket([]byte(undoBucket)).Put(hash, e.Bytes())
+}
+
+func getUndo(btx *bolt.Tx, hash []byte) ([]tx.UTXO, error) {
+	encoded := btx.Bucket([]byte(undoBucket)).Get(hash)
+	if encoded == nil {
+		return nil, fmt.Errorf("%w: no undo data for block %x", ErrBlockPruned, hash)
+	}
+
+	d := canonical.NewDecoder(encoded)
+	spent := make([]tx.UTXO, d.ReadCount())
+	for i := range spent {
+		key := d.ReadBytes()
+		value := d.ReadBytes()
+		if d.Err() != nil {
+			break
+		}
+
+		var err error
+		spent[i], err = decodeUTXO(key, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := d.Finish(); err != nil {
+		return nil, fmt.Errorf("failed to decode undo data of block %x: %v", hash, err)
+	}
+
+	return spent, nil
+}
+
+// Reverse connectBlock: remove the outputs created by the block and restore
+// the outputs it spent from its undo record, which is then deleted
+func disconnectBlock(btx *bolt.Tx, block *Block) error {
+	spent, err := getUndo(btx, block.Hash)
+	if err != nil {
+		return err
+	}
+
+	b := btx.Bucket([]byte(utxoBucket))
+
+	// transactions are undone last first, as a later transaction may spend
+	// outputs of an earlier one
+	for i := len(block.Transactions) - 1; i >= 0; i-- {
+		t := block.Transactions[i]
+
+		for index := range t.Vout {
+			key := utxoKey(t.ID, index)
+			if b.Get(key) == nil {
+				return fmt.Errorf("block %x created output %s which is not in the UTXO index", block.Hash, Outpoint{t.ID, index})
+			}
+			if err := b.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		if t.IsCoinbase() {
+			continue
+		}
+		for j := len(t.Vin) - 1; j >= 0; j-- {
+			if len(spent) == 0 {
+				return fmt.Errorf("undo data of block %x is missing spent outputs", block.Hash)
+			}
+			utxo := spent[len(spent)-1]
+			spent = spent[:len(spent)-1]
+			if err := b.Put(utxoKey(utxo.TxID, utxo.Index), encodeUTXO(utxo)); err != nil {
+				return err
+			}
+		}
+	}
+	if len(spent) != 0 {
+		return fmt.Errorf("undo data of block %x holds %d extra outputs", block.Hash, len(spent))
+	}
+
+	return btx.Bucket([]byte(undoBucket)).Delete(block.Hash)
+}
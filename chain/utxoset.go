@@ -0,0 +1,245 @@
+package chain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/jplesperance/tcn/encoding/canonical"
+	"github.com/jplesperance/tcn/tx"
+)
+
+// The UTXO index holds every unspent output of the chain, so that balances,
+// coin selection and transaction validation never read block bodies, which
+// pruning deletes.  Entries are keyed by outpoint, the transaction ID
+// followed by the output index as a 4 byte big-endian integer, and hold the
+// height of the block that created the output, its value in sub-units, its
+// public key hash and its output type.
+const utxoBucket = "utxo"
+
+func utxoKey(txID []byte, index int) []byte {
+	key := make([]byte, len(txID)+4)
+	copy(key, txID)
+	binary.BigEndian.PutUint32(key[len(txID):], uint32(index))
+
+	return key
+}
+
+func encodeUTXO(utxo tx.UTXO) []byte {
+	var e canonical.Encoder
+	e.WriteUvarint(uint64(utxo.Height))
+	e.WriteVarint(int64(utxo.Output.Value))
+	e.WriteBytes(utxo.Output.PubKeyHash)
+	e.WriteUvarint(uint64(utxo.Output.Type))
+
+	return e.Bytes()
+}
+
+func decodeUTXO(key, value []byte) (tx.UTXO, error) {
+	if len(key) < 4 {
+		return tx.UTXO{}, fmt.Errorf("invalid UTXO index key %x", key)
+	}
+
+	utxo := tx.UTXO{
+		TxID:  append([]byte{}, key[:len(key)-4]...),
+		Index: int(binary.BigEndian.Uint32(key[len(key)-4:])),
+	}
+
+	d := canonical.NewDecoder(value)
+	utxo.Height = d.ReadInt()
+	utxo.Output.Value = tx.Amount(d.ReadVarint())
+	utxo.Output.PubKeyHash = d.ReadBytes()
+	utxo.Output.Type = tx.OutputType(d.ReadUvarint())
+	if d.Err() == nil && !utxo.Output.Type.Valid() {
+		d.Fail(fmt.Errorf("unknown output type %d", utxo.Output.Type))
+	}
+	if err := d.Finish(); err != nil {
+		return tx.UTXO{}, fmt.Errorf("failed to decode UTXO %x: %v", key, err)
+	}
+
+	return utxo, nil
+}
+
+// Apply a block to the UTXO index, removing the outputs its transactions
+// spend and adding the outputs they create, and store its undo record
+func connectBlock(btx *bolt.Tx, block *Block) error {
+	b := btx.Bucket([]byte(utxoBucket))
+	var spent []tx.UTXO
+
+	for _, t := range block.Transactions {
+		if !t.IsCoinbase() {
+			for _, vin := range t.Vin {
+				key := utxoKey(vin.Txid, vin.Vout)
+				value := b.Get(key)
+				if value == nil {
+					return fmt.Errorf("block %x spends output %s which is not in the UTXO index", block.Hash, Outpoint{vin.Txid, vin.Vout})
+				}
+				utxo, err := decodeUTXO(key, value)
+				if err != nil {
+					return err
+				}
+				spent = append(spent, utxo)
+				if err := b.Delete(key); err != nil {
+					return err
+				}
+			}
+		}
+
+		for index, out := range t.Vout {
+			key := utxoKey(t.ID, index)
+			if b.Get(key) != nil {
+				return fmt.Errorf("block %x creates output %s which is already unspent", block.Hash, Outpoint{t.ID, index})
+			}
+			utxo := tx.UTXO{TxID: t.ID, Index: index, Output: out, Height: block.Height}
+			if err := b.Put(key, encodeUTXO(utxo)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return putUndo(btx, block.Hash, spent)
+}
+
+// Rebuild the UTXO index and the undo records from the block bodies,
+// returning the number of blocks applied
+func reindexUTXO(btx *bolt.Tx) (int, error) {
+	for _, bucket := range []string{utxoBucket, undoBucket} {
+		if btx.Bucket([]byte(bucket)) != nil {
+			if err := btx.DeleteBucket([]byte(bucket)); err != nil {
+				return 0, err
+			}
+		}
+		if _, err := btx.CreateBucket([]byte(bucket)); err != nil {
+			return 0, err
+		}
+	}
+
+	var hashes [][]byte
+	for hash := btx.Bucket([]byte(blocksBucket)).Get([]byte("l")); len(hash) != 0; {
+		header, err := getHeader(btx, hash)
+		if err != nil {
+			return 0, err
+		}
+		hashes = append(hashes, hash)
+		hash = header.PrevBlockHash
+	}
+
+	for i := len(hashes) - 1; i >= 0; i-- {
+		block, err := getBlock(btx, hashes[i])
+		if err != nil {
+			return 0, err
+		}
+		if err := connectBlock(btx, block); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(hashes), nil
+}
+
+// GetUTXO returns the unspent output at index of the transaction txID, or
+// nil if it does not exist or is spent
+func (bc *Blockchain) GetUTXO(txID []byte, index int) (*tx.UTXO, error) {
+	var utxo *tx.UTXO
+
+	err := bc.db.View(func(btx *bolt.Tx) error {
+		var err error
+		utxo, err = getUTXO(btx, txID, index)
+		return err
+	})
+
+	return utxo, err
+}
+
+func getUTXO(btx *bolt.Tx, txID []byte, index int) (*tx.UTXO, error) {
+	key := utxoKey(txID, index)
+	value := btx.Bucket([]byte(utxoBucket)).Get(key)
+	if value == nil {
+		return nil, nil
+	}
+
+	utxo, err := decodeUTXO(key, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &utxo, nil
+}
+
+// Return the transactions whose outputs are spent by t, keyed by hex ID, as
+// tx.Sign and tx.Verify expect them.  The transactions are rebuilt from the
+// UTXO index, so only the outputs spent by t are filled in.
+func (bc *Blockchain) prevTransactions(t *tx.Transaction) (map[string]tx.Transaction, error) {
+	prevTXs := make(map[string]tx.Transaction)
+
+	for _, vin := range t.Vin {
+		utxo, err := bc.GetUTXO(vin.Txid, vin.Vout)
+		if err != nil {
+			return nil, err
+		}
+		if utxo == nil {
+			return nil, fmt.Errorf("%w: unspent output %s", tx.ErrTxNotFound, Outpoint{vin.Txid, vin.Vout})
+		}
+		addPrevOutput(prevTXs, *utxo)
+	}
+
+	return prevTXs, nil
+}
+
+// Add an output taken from the UTXO index to the transaction holding it in
+// prevTXs, leaving the other outputs of that transaction empty
+func addPrevOutput(prevTXs map[string]tx.Transaction, utxo tx.UTXO) {
+	id := hex.EncodeToString(utxo.TxID)
+	prevTX := prevTXs[id]
+	prevTX.ID = utxo.TxID
+	for len(prevTX.Vout) <= utxo.Index {
+		prevTX.Vout = append(prevTX.Vout, tx.TXOutput{})
+	}
+	prevTX.Vout[utxo.Index] = utxo.Output
+	prevTXs[id] = prevTX
+}
+
+// Collect the unspent outputs locked to the public key hash
+func (bc *Blockchain) FindUTXO(pubKeyHash []byte) ([]tx.TXOutput, error) {
+	var UTXOs []tx.TXOutput
+	utxos, err := bc.FindUTXOs([][]byte{pubKeyHash})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, utxo := range utxos {
+		UTXOs = append(UTXOs, utxo.Output)
+	}
+	return UTXOs, nil
+}
+
+// Collect the unspent outputs locked to any of the given public key hashes
+func (bc *Blockchain) FindUTXOs(pubKeyHashes [][]byte) ([]tx.UTXO, error) {
+	var utxos []tx.UTXO
+
+	ownsKey := func(pubKeyHash []byte) bool {
+		for _, hash := range pubKeyHashes {
+			if bytes.Equal(hash, pubKeyHash) {
+				return true
+			}
+		}
+		return false
+	}
+
+	err := bc.db.View(func(btx *bolt.Tx) error {
+		return btx.Bucket([]byte(utxoBucket)).ForEach(func(k, v []byte) error {
+			utxo, err := decodeUTXO(k, v)
+			if err != nil {
+				return err
+			}
+			if ownsKey(utxo.Output.PubKeyHash) {
+				utxos = append(utxos, utxo)
+			}
+			return nil
+		})
+	})
+
+	return utxos, err
+}
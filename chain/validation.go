@@ -0,0 +1,385 @@
+package chain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/jplesperance/tcn/encoding/ecc"
+	"github.com/jplesperance/tcn/tx"
+)
+
+// Outpoint identifies a single transaction output
+type Outpoint struct {
+	Txid []byte
+	Vout int
+}
+
+func (o Outpoint) String() string {
+	return fmt.Sprintf("%x:%d", o.Txid, o.Vout)
+}
+
+func (o Outpoint) key() string {
+	return fmt.Sprintf("%s:%d", hex.EncodeToString(o.Txid), o.Vout)
+}
+
+// MissingOutputError reports an input referencing an output that does not
+// exist in the chain or earlier in the block, or whose spender has been
+// pruned
+type MissingOutputError struct {
+	Tx       []byte
+	Outpoint Outpoint
+}
+
+func (e *MissingOutputError) Error() string {
+	return fmt.Sprintf("transaction %x spends nonexistent output %s", e.Tx, e.Outpoint)
+}
+
+func (e *MissingOutputError) Unwrap() error { return tx.ErrInvalidTransaction }
+
+// SpentOutputError reports an input referencing an output already spent by
+// a transaction in the chain
+type SpentOutputError struct {
+	Tx       []byte
+	Outpoint Outpoint
+	SpentBy  []byte
+}
+
+func (e *SpentOutputError) Error() string {
+	return fmt.Sprintf("transaction %x spends output %s already spent by %x", e.Tx, e.Outpoint, e.SpentBy)
+}
+
+func (e *SpentOutputError) Unwrap() error { return ErrDoubleSpend }
+
+// DuplicateInputError reports a transaction spending the same output twice
+type DuplicateInputError struct {
+	Tx       []byte
+	Outpoint Outpoint
+}
+
+func (e *DuplicateInputError) Error() string {
+	return fmt.Sprintf("transaction %x spends output %s more than once", e.Tx, e.Outpoint)
+}
+
+func (e *DuplicateInputError) Unwrap() error { return ErrDoubleSpend }
+
+// BlockConflictError reports two transactions in the same block spending
+// the same output
+type BlockConflictError struct {
+	Tx       []byte
+	Other    []byte
+	Outpoint Outpoint
+}
+
+func (e *BlockConflictError) Error() string {
+	return fmt.Sprintf("transactions %x and %x in the same block both spend output %s", e.Other, e.Tx, e.Outpoint)
+}
+
+func (e *BlockConflictError) Unwrap() error { return ErrDoubleSpend }
+
+// OutputsExceedInputsError reports a transaction creating more value than
+// it spends
+type OutputsExceedInputsError struct {
+	Tx      []byte
+	Inputs  tx.Amount
+	Outputs tx.Amount
+}
+
+func (e *OutputsExceedInputsError) Error() string {
+	return fmt.Sprintf("transaction %x outputs %s exceed inputs %s", e.Tx, e.Outputs, e.Inputs)
+}
+
+func (e *OutputsExceedInputsError) Unwrap() error { return tx.ErrInvalidTransaction }
+
+// CoinbaseError reports a block whose coinbase is missing, misplaced or
+// creates more than the block subsidy and fees
+type CoinbaseError struct {
+	Tx     []byte
+	Reason string
+}
+
+func (e *CoinbaseError) Error() string {
+	if e.Tx == nil {
+		return fmt.Sprintf("invalid coinbase: %s", e.Reason)
+	}
+	return fmt.Sprintf("invalid coinbase %x: %s", e.Tx, e.Reason)
+}
+
+func (e *CoinbaseError) Unwrap() error { return tx.ErrInvalidTransaction }
+
+// ValidateTransactions checks transactions to be mined into a new block, in
+// block order, against the chain: none may be a coinbase, every input must
+// reference an existing output that is unspent in the chain and not spent
+// by an earlier transaction in the block or earlier in the same
+// transaction, outputs may not exceed inputs, and signatures must verify.
+// A transaction may spend outputs of transactions before it in the block.
+// Only transactions of tx.CurrentVersion can be mined.
+func (bc *Blockchain) ValidateTransactions(transactions []*tx.Transaction) error {
+	for _, t := range transactions {
+		if t.IsCoinbase() {
+			return &CoinbaseError{t.ID, "the block's coinbase is added by the miner"}
+		}
+	}
+	if err := checkTransactionVersions(transactions); err != nil {
+		return err
+	}
+
+	return bc.db.View(func(btx *bolt.Tx) error {
+		_, err := validateTransactions(btx, transactions, true)
+		return err
+	})
+}
+
+// Validate transactions as ValidateTransactions against the chain as seen by
+// btx, skipping coinbases and the signature checks unless verifySignatures
+// is set, and return the total fee they pay
+func validateTransactions(btx *bolt.Tx, transactions []*tx.Transaction, verifySignatures bool) (tx.Amount, error) {
+	v := &blockValidation{
+		btx:        btx,
+		blockTXs:   make(map[string]*tx.Transaction),
+		prevTXs:    make(map[string]tx.Transaction),
+		blockSpent: make(map[string][]byte),
+	}
+
+	for _, t := range transactions {
+		if err := t.CheckSanity(); err != nil {
+			return 0, fmt.Errorf("%w: %v", tx.ErrInvalidTransaction, err)
+		}
+		if !t.IsCoinbase() {
+			if err := v.validateTransaction(t, verifySignatures); err != nil {
+				return 0, err
+			}
+		}
+		v.blockTXs[hex.EncodeToString(t.ID)] = t
+	}
+
+	if err := v.signatures.verify(); err != nil {
+		return 0, err
+	}
+
+	return v.fees, nil
+}
+
+// blockValidation holds the state of validating the transactions of a
+// block in order
+//
+// blockTXs: transactions earlier in the block, whose outputs may be spent
+// prevTXs: transactions holding the spent outputs, for signature checks
+// blockSpent: outputs spent so far in the block, mapped to the spending
+// transaction
+// signatures: Schnorr signatures of the block, checked once the rest is
+// valid
+// fees: total fee of the transactions validated so far
+type blockValidation struct {
+	btx        *bolt.Tx
+	blockTXs   map[string]*tx.Transaction
+	prevTXs    map[string]tx.Transaction
+	blockSpent map[string][]byte
+	signatures blockSignatures
+	fees       tx.Amount
+}
+
+// Validate the non-coinbase transaction t and add its fee
+func (v *blockValidation) validateTransaction(t *tx.Transaction, verifySignatures bool) error {
+	inputs, txSpent, err := v.spendInputs(t)
+	if err != nil {
+		return err
+	}
+
+	outputs, err := t.OutputValue()
+	if err != nil {
+		return fmt.Errorf("%w: transaction %x outputs: %v", tx.ErrInvalidTransaction, t.ID, err)
+	}
+	if outputs > inputs {
+		return &OutputsExceedInputsError{t.ID, inputs, outputs}
+	}
+	fee, err := inputs.Sub(outputs)
+	if err == nil {
+		v.fees, err = v.fees.Add(fee)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: transaction %x fee: %v", tx.ErrInvalidTransaction, t.ID, err)
+	}
+
+	if verifySignatures {
+		if err := v.signatures.verifyTransaction(t, v.prevTXs); err != nil {
+			return err
+		}
+	}
+
+	for key := range txSpent {
+		v.blockSpent[key] = t.ID
+	}
+
+	return nil
+}
+
+// Check that the inputs of t spend distinct available outputs, and return
+// their total value and the keys of the outputs they spend
+func (v *blockValidation) spendInputs(t *tx.Transaction) (tx.Amount, map[string]bool, error) {
+	var inputs tx.Amount
+	txSpent := make(map[string]bool)
+
+	for _, vin := range t.Vin {
+		outpoint := Outpoint{vin.Txid, vin.Vout}
+		key := outpoint.key()
+
+		if txSpent[key] {
+			return 0, nil, &DuplicateInputError{t.ID, outpoint}
+		}
+		txSpent[key] = true
+
+		output, err := v.prevOutput(t, outpoint)
+		if err != nil {
+			return 0, nil, err
+		}
+		if other, ok := v.blockSpent[key]; ok {
+			return 0, nil, &BlockConflictError{t.ID, other, outpoint}
+		}
+
+		inputs, err = inputs.Add(output.Value)
+		if err != nil {
+			return 0, nil, fmt.Errorf("%w: transaction %x inputs: %v", tx.ErrInvalidTransaction, t.ID, err)
+		}
+	}
+
+	return inputs, txSpent, nil
+}
+
+// Return the output spent by an input of t, from a transaction earlier in
+// the block or from the UTXO index
+func (v *blockValidation) prevOutput(t *tx.Transaction, outpoint Outpoint) (tx.TXOutput, error) {
+	if blockTX, ok := v.blockTXs[hex.EncodeToString(outpoint.Txid)]; ok {
+		if outpoint.Vout < 0 || outpoint.Vout >= len(blockTX.Vout) {
+			return tx.TXOutput{}, &MissingOutputError{t.ID, outpoint}
+		}
+		v.prevTXs[hex.EncodeToString(outpoint.Txid)] = *blockTX
+		return blockTX.Vout[outpoint.Vout], nil
+	}
+
+	utxo, err := getUTXO(v.btx, outpoint.Txid, outpoint.Vout)
+	if err != nil {
+		return tx.TXOutput{}, err
+	}
+	if utxo == nil {
+		return tx.TXOutput{}, unavailableOutputError(v.btx, t.ID, outpoint)
+	}
+	addPrevOutput(v.prevTXs, *utxo)
+
+	return utxo.Output, nil
+}
+
+// Check that a new block holds only transactions of tx.CurrentVersion.
+// Older versions, whose signatures cover less, stay valid in blocks stored
+// before the upgrade but cannot be mined or imported into new blocks.
+func checkTransactionVersions(transactions []*tx.Transaction) error {
+	for _, t := range transactions {
+		if t.Version < tx.CurrentVersion {
+			return fmt.Errorf("%w: transaction %x has version %d, new blocks only accept version %d", tx.ErrInvalidTransaction, t.ID, t.Version, tx.CurrentVersion)
+		}
+	}
+
+	return nil
+}
+
+// Check the coinbase of a block's transactions, which pay fees in total:
+// the first transaction, and only that one, must be a coinbase, creating at
+// most the subsidy and the fees
+func checkCoinbase(transactions []*tx.Transaction, fees tx.Amount) error {
+	if len(transactions) == 0 || !transactions[0].IsCoinbase() {
+		return &CoinbaseError{nil, "the first transaction of the block is not a coinbase"}
+	}
+	for _, t := range transactions[1:] {
+		if t.IsCoinbase() {
+			return &CoinbaseError{t.ID, "only the first transaction of a block may be a coinbase"}
+		}
+	}
+
+	coinbase := transactions[0]
+	reward, err := tx.Subsidy.Add(fees)
+	if err != nil {
+		return &CoinbaseError{coinbase.ID, err.Error()}
+	}
+	value, err := coinbase.OutputValue()
+	if err != nil {
+		return &CoinbaseError{coinbase.ID, err.Error()}
+	}
+	if value > reward {
+		return &CoinbaseError{coinbase.ID, fmt.Sprintf("it creates %s, more than the subsidy and fees of %s", value, reward)}
+	}
+
+	return nil
+}
+
+// blockSignatures queues the Schnorr signatures of a block's transactions
+// to check them as a batch at the end, see ecc.SchnorrVerifier
+type blockSignatures struct {
+	verifier ecc.SchnorrVerifier
+	// ID of the transaction of each queued signature
+	txIDs [][]byte
+}
+
+// Verify the signatures of t, queueing its Schnorr signatures
+func (b *blockSignatures) verifyTransaction(t *tx.Transaction, prevTXs map[string]tx.Transaction) error {
+	if err := t.VerifyWith(prevTXs, &b.verifier); err != nil {
+		return err
+	}
+	for len(b.txIDs) < b.verifier.Len() {
+		b.txIDs = append(b.txIDs, t.ID)
+	}
+
+	return nil
+}
+
+// Verify the queued signatures, reporting the transaction of an invalid one
+func (b *blockSignatures) verify() error {
+	err := b.verifier.Verify()
+	var verifierErr *ecc.VerifierError
+	if errors.As(err, &verifierErr) {
+		return &tx.InvalidSignatureError{Tx: b.txIDs[verifierErr.Index]}
+	}
+
+	return err
+}
+
+// Explain why an output spent by the transaction txID is not in the UTXO
+// index, walking the chain from the tip for a transaction that spent it.
+// The walk stops at the first pruned block, past which the output is
+// reported missing.
+func unavailableOutputError(btx *bolt.Tx, txID []byte, outpoint Outpoint) error {
+	hash := btx.Bucket([]byte(blocksBucket)).Get([]byte("l"))
+	for len(hash) != 0 {
+		block, err := getBlock(btx, hash)
+		if errors.Is(err, ErrBlockPruned) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if spentBy := spenderOf(block, outpoint); spentBy != nil {
+			return &SpentOutputError{txID, outpoint, spentBy}
+		}
+		hash = block.PrevBlockHash
+	}
+
+	return &MissingOutputError{txID, outpoint}
+}
+
+// Return the ID of the transaction of block spending outpoint, nil if none
+// does
+func spenderOf(block *Block, outpoint Outpoint) []byte {
+	for _, t := range block.Transactions {
+		if t.IsCoinbase() {
+			continue
+		}
+		for _, vin := range t.Vin {
+			if bytes.Equal(vin.Txid, outpoint.Txid) && vin.Vout == outpoint.Vout {
+				return t.ID
+			}
+		}
+	}
+
+	return nil
+}
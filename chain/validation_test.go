@@ -0,0 +1,139 @@
+package chain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/jplesperance/tcn/network"
+	"github.com/jplesperance/tcn/tx"
+	"github.com/jplesperance/tcn/wallet"
+)
+
+// Create a test network chain whose mined block pays w, returning the
+// coinbase of that block
+func createFundedChain(t *testing.T, w *wallet.Wallet) (*Blockchain, *tx.Transaction) {
+	t.Helper()
+
+	opts := Options{DBFile: filepath.Join(t.TempDir(), "blockchain.db"), Params: &network.Test}
+	bc, err := CreateBlockchain(opts, string(w.GetAddress()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bc.Close() })
+
+	tip, err := bc.GetBlock(bc.Tip())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bc, tip.Transactions[0]
+}
+
+// Return an unsigned transaction of w spending outpoints to outputs of the
+// given values paying w
+func unsignedSpend(t *testing.T, w *wallet.Wallet, outpoints []Outpoint, values ...tx.Amount) *tx.Transaction {
+	t.Helper()
+
+	spend := &tx.Transaction{Version: tx.CurrentVersion}
+	for _, outpoint := range outpoints {
+		spend.Vin = append(spend.Vin, tx.TXInput{Txid: outpoint.Txid, Vout: outpoint.Vout, PubKey: w.PublicKey})
+	}
+	for _, value := range values {
+		output, err := tx.NewTXOutput(value, string(w.GetAddress()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		spend.Vout = append(spend.Vout, *output)
+	}
+	spend.ID = spend.ComputeID()
+
+	return spend
+}
+
+// Return a transaction of w spending outpoints, which are outputs of prevs,
+// to outputs of the given values paying w
+func signedSpend(t *testing.T, w *wallet.Wallet, prevs []*tx.Transaction, outpoints []Outpoint, values ...tx.Amount) *tx.Transaction {
+	t.Helper()
+
+	spend := unsignedSpend(t, w, outpoints, values...)
+	prevTXs := make(map[string]tx.Transaction)
+	for _, prev := range prevs {
+		prevTXs[hex.EncodeToString(prev.ID)] = *prev
+	}
+	if err := spend.Sign(w.PrivateKey, prevTXs); err != nil {
+		t.Fatal(err)
+	}
+
+	return spend
+}
+
+func TestValidateTransactions(t *testing.T) {
+	w, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc, coinbase := createFundedChain(t, w)
+	funds := []*tx.Transaction{coinbase}
+	reward := Outpoint{coinbase.ID, 0}
+
+	spend := signedSpend(t, w, funds, []Outpoint{reward}, 6*tx.Coin, 3*tx.Coin)
+	child := signedSpend(t, w, []*tx.Transaction{spend}, []Outpoint{{spend.ID, 1}}, 2*tx.Coin)
+	other := signedSpend(t, w, funds, []Outpoint{reward}, 9*tx.Coin)
+	twice := signedSpend(t, w, funds, []Outpoint{reward, reward}, 9*tx.Coin)
+	overspend := signedSpend(t, w, funds, []Outpoint{reward}, 11*tx.Coin)
+	// outputs that do not exist cannot be signed for
+	missing := unsignedSpend(t, w, []Outpoint{{coinbase.ID, 1}}, tx.Coin)
+	missingParent := unsignedSpend(t, w, []Outpoint{{spend.ID, 2}}, tx.Coin)
+
+	tests := []struct {
+		name         string
+		transactions []*tx.Transaction
+		target       interface{}
+		sentinel     error
+	}{
+		{"spend", []*tx.Transaction{spend}, nil, nil},
+		{"spend of an output earlier in the block", []*tx.Transaction{spend, child}, nil, nil},
+		{"spend of an output later in the block", []*tx.Transaction{child, spend}, new(*MissingOutputError), tx.ErrInvalidTransaction},
+		{"output spent twice by a transaction", []*tx.Transaction{twice}, new(*DuplicateInputError), ErrDoubleSpend},
+		{"output spent by two transactions", []*tx.Transaction{spend, other}, new(*BlockConflictError), ErrDoubleSpend},
+		{"missing output", []*tx.Transaction{missing}, new(*MissingOutputError), tx.ErrInvalidTransaction},
+		{"missing output of a block transaction", []*tx.Transaction{spend, missingParent}, new(*MissingOutputError), tx.ErrInvalidTransaction},
+		{"outputs exceeding inputs", []*tx.Transaction{overspend}, new(*OutputsExceedInputsError), tx.ErrInvalidTransaction},
+		{"coinbase", []*tx.Transaction{coinbase}, new(*CoinbaseError), tx.ErrInvalidTransaction},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := bc.ValidateTransactions(tt.transactions)
+			if tt.target == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.As(err, tt.target) {
+				t.Fatalf("got %v, want a %T", err, tt.target)
+			}
+			if !errors.Is(err, tt.sentinel) {
+				t.Fatalf("%v does not wrap %v", err, tt.sentinel)
+			}
+		})
+	}
+
+	t.Run("output spent in the chain", func(t *testing.T) {
+		if _, err := bc.MineBlock([]*tx.Transaction{spend}, string(w.GetAddress())); err != nil {
+			t.Fatal(err)
+		}
+
+		var spentErr *SpentOutputError
+		if err := bc.ValidateTransactions([]*tx.Transaction{other}); !errors.As(err, &spentErr) {
+			t.Fatalf("got %v, want a *SpentOutputError", err)
+		}
+		if !bytes.Equal(spentErr.SpentBy, spend.ID) || !errors.Is(spentErr, ErrDoubleSpend) {
+			t.Fatalf("spent by %x, want %x", spentErr.SpentBy, spend.ID)
+		}
+	})
+}
@@ -0,0 +1,1154 @@
+// Package cli implements the tcn command line interface
+package cli
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jplesperance/tcn/chain"
+	"github.com/jplesperance/tcn/encoding/ecc"
+	"github.com/jplesperance/tcn/logging"
+	"github.com/jplesperance/tcn/network"
+	"github.com/jplesperance/tcn/tx"
+	"github.com/jplesperance/tcn/wallet"
+)
+
+// CLI parses the command line and runs the requested command
+//
+// json: print results as JSON instead of text
+// prune: prune depth given with -prune, nil if the flag was not given
+// params: parameters of the network selected with -network
+type CLI struct {
+	json   bool
+	prune  *int
+	params *network.Params
+}
+
+// Exit codes returned by the CLI
+const (
+	exitOK = iota
+	exitError
+	exitUsage
+	exitChain
+	exitWallet
+	exitFunds
+	exitInvalid
+)
+
+// Map an error returned by a command to the process exit code
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.Is(err, chain.ErrChainNotFound), errors.Is(err, chain.ErrChainExists),
+		errors.Is(err, chain.ErrOutdatedFormat), errors.Is(err, chain.ErrBlockNotFound),
+		errors.Is(err, chain.ErrBlockPruned):
+		return exitChain
+	case errors.Is(err, wallet.ErrWalletFileNotFound), errors.Is(err, wallet.ErrWalletNotFound),
+		errors.Is(err, wallet.ErrInvalidAddress), errors.Is(err, wallet.ErrInvalidPrivateKey),
+		errors.Is(err, wallet.ErrInvalidPublicKey), errors.Is(err, wallet.ErrWatchOnly),
+		errors.Is(err, wallet.ErrInvalidVanityPrefix):
+		return exitWallet
+	case errors.Is(err, chain.ErrInsufficientFunds):
+		return exitFunds
+	case errors.Is(err, tx.ErrInvalidTransaction), errors.Is(err, tx.ErrInvalidSignature),
+		errors.Is(err, chain.ErrDoubleSpend), errors.Is(err, tx.ErrTxNotFound),
+		errors.Is(err, chain.ErrCheckpoint), errors.Is(err, wallet.ErrInvalidMessageSignature):
+		return exitInvalid
+	default:
+		return exitError
+	}
+}
+
+// Outputs with fewer confirmations are reported as unconfirmed by
+// getwalletbalance, as the blocks holding them may still be replaced
+const defaultMinConf = 6
+
+// errUsage reports a command invoked with missing or malformed arguments
+var errUsage = errors.New("invalid arguments")
+
+// Return the options selecting the blockchain of the network
+func (cli *CLI) chainOptions() chain.Options {
+	return chain.Options{Params: cli.params}
+}
+
+// Return the options selecting the wallet file of the network
+func (cli *CLI) walletOptions() wallet.Options {
+	return wallet.Options{File: cli.params.WalletFile}
+}
+
+// Open the existing blockchain, applying -prune if given
+func (cli *CLI) openBlockchain() (*chain.Blockchain, error) {
+	bc, err := chain.NewBlockchain(cli.chainOptions())
+	if err != nil {
+		return nil, err
+	}
+	if err := cli.applyPrune(bc); err != nil {
+		bc.Close()
+		return nil, err
+	}
+
+	return bc, nil
+}
+
+// Store the depth given with -prune in the blockchain, pruning it
+func (cli *CLI) applyPrune(bc *chain.Blockchain) error {
+	if cli.prune == nil {
+		return nil
+	}
+
+	return bc.SetPruneDepth(*cli.prune)
+}
+
+func (cli *CLI) createBlockchain(address string) error {
+	if !wallet.ValidateAddress(address) {
+		return wallet.ErrInvalidAddress
+	}
+	bc, err := chain.CreateBlockchain(cli.chainOptions(), address)
+	if err != nil {
+		return err
+	}
+	defer bc.Close()
+	if err := cli.applyPrune(bc); err != nil {
+		return err
+	}
+
+	genesis := chain.NewGenesisBlock(cli.params)
+	result := createBlockchainResult{hex.EncodeToString(genesis.Hash), hex.EncodeToString(bc.Tip())}
+
+	return cli.output(result, func() {
+		fmt.Println("Done!")
+	})
+}
+
+func (cli *CLI) getBalance(address string) error {
+	pubKeyHash, err := wallet.DecodeAddress(address)
+	if err != nil {
+		return err
+	}
+	bc, err := cli.openBlockchain()
+	if err != nil {
+		return err
+	}
+	defer bc.Close()
+
+	balance, err := balanceOf(bc, pubKeyHash)
+	if err != nil {
+		return err
+	}
+
+	// the wallet file is only read to flag watch-only addresses, any
+	// address can be queried
+	watchOnly := false
+	wallets, err := wallet.NewWallets(cli.walletOptions())
+	defer wallets.Close()
+	if err == nil {
+		watchOnly = wallets.IsWatchOnly(address)
+	}
+
+	return cli.output(balanceResult{address, balance, watchOnly}, func() {
+		if watchOnly {
+			fmt.Printf("Balance of '%s' (watch-only): %s\n", address, balance)
+		} else {
+			fmt.Printf("Balance of '%s': %s\n", address, balance)
+		}
+	})
+}
+
+// Return the total value of the unspent outputs locked to pubKeyHash
+func balanceOf(bc *chain.Blockchain, pubKeyHash []byte) (tx.Amount, error) {
+	var balance tx.Amount
+	UTXOs, err := bc.FindUTXO(pubKeyHash)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, out := range UTXOs {
+		balance, err = balance.Add(out.Value)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return balance, nil
+}
+
+// Usage is written to stderr so that stdout only carries command results
+func (cli *CLI) printUsage() {
+	w := os.Stderr
+	fmt.Fprintln(w, "Usage: tcn [-json] [-network NETWORK] [-prune DEPTH] [-loglevel LEVEL] [-logformat FORMAT] COMMAND [ARGS]")
+	fmt.Fprintln(w, "  -json prints command results as JSON")
+	fmt.Fprintln(w, "  NETWORK selects the checkpoints and defaults: main or test (default main)")
+	fmt.Fprintf(w, "  -prune DEPTH deletes the bodies of blocks more than DEPTH (0 or at least %d) below the tip; the setting is kept\n", chain.MinPruneDepth)
+	fmt.Fprintln(w, "  LEVEL is debug, info, warn or error, optionally followed by per subsystem")
+	fmt.Fprintf(w, "      levels, e.g. warn,pow=debug. Subsystems: %s (default info)\n", strings.Join(logging.Subsystems, ", "))
+	fmt.Fprintln(w, "  FORMAT is text or json (default text). Logs are written to stderr")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	fmt.Fprintln(w, "  getbalance -address ADDRESS - Get balance of ADDRESS")
+	fmt.Fprintln(w, "  getwalletbalance [-minconf N] - Get the balance of the whole wallet file, split by at least N (default 6) confirmations")
+	fmt.Fprintln(w, "  listunspent [-minconf N] - List the unspent outputs of the wallet file with at least N (default 1) confirmations")
+	fmt.Fprintln(w, "  createwallet [-label LABEL] [-schnorr] - Generates a new key-pair and saves it into the wallet file")
+	fmt.Fprintln(w, "      -schnorr creates a key signing with Schnorr signatures, whose address receives Schnorr outputs")
+	fmt.Fprintln(w, "  vanityaddress -prefix PREFIX [-workers N] [-schnorr] [-label LABEL] - Generate keys on N workers (default one per CPU) until an address starts with PREFIX and save it into the wallet file")
+	fmt.Fprintln(w, "  listaddresses - Lists all the addresses from the wallet file with their labels and balances")
+	fmt.Fprintln(w, "  setlabel -address ADDRESS -label LABEL - Label ADDRESS in the wallet file, an empty LABEL removes it")
+	fmt.Fprintln(w, "  backupwallet -file FILE - Copy the wallet file to FILE")
+	fmt.Fprintln(w, "  dumpprivkey -address ADDRESS - Print the private key of ADDRESS, encoded for the network")
+	fmt.Fprintln(w, "  importprivkey -key KEY [-rescan] - Add a private key printed by dumpprivkey to the wallet file")
+	fmt.Fprintln(w, "      -rescan reports the balance the chain holds for the key")
+	fmt.Fprintln(w, "  signmessage -address ADDRESS -message MESSAGE - Sign MESSAGE with the private key of ADDRESS")
+	fmt.Fprintln(w, "  verifymessage -address ADDRESS -signature SIGNATURE -message MESSAGE - Check that ADDRESS signed MESSAGE")
+	fmt.Fprintln(w, "  importaddress -address ADDRESS [-rescan] - Watch ADDRESS without its private key; it is reported but never spent")
+	fmt.Fprintln(w, "  importpubkey -pubkey HEX [-rescan] - Watch the address of a hex encoded public key without its private key")
+	fmt.Fprintln(w, "  getpubkey -address ADDRESS - Print the hex encoded public key of ADDRESS")
+	fmt.Fprintln(w, "  aggregatekeys -pubkeys HEX,HEX,... - Print the Schnorr key aggregating the Schnorr public keys and its address")
+	fmt.Fprintln(w, "  createblockchain -address ADDRESS - Create a test network blockchain from its genesis block and send the reward of its first block to ADDRESS")
+	fmt.Fprintln(w, "  printchain [-headers] - Print all the blocks of the blockchain, or only their headers")
+	fmt.Fprintln(w, "  getblock -hash HASH - Print the block with the given hash")
+	fmt.Fprintln(w, "  verifyheaders - Check the proof of work and linkage of every block header")
+	fmt.Fprintln(w, "  getchaininfo - Print the height and tip of the blockchain and how far it is pruned")
+	fmt.Fprintln(w, "  invalidateblock -hash HASH - Disconnect block HASH and its descendants, restoring the outputs they spent")
+	fmt.Fprintln(w, "  reconsiderblock -hash HASH - Undo invalidateblock, reconnecting the chain of block HASH if it is longer")
+	fmt.Fprintln(w, "  exportchain -file FILE [-from HEIGHT] [-to HEIGHT] - Write the blocks from HEIGHT to HEIGHT (default the whole chain) to a bootstrap FILE")
+	fmt.Fprintln(w, "  importchain -file FILE [-assumevalid HASH] - Create a blockchain from a bootstrap FILE, validating every block")
+	fmt.Fprintln(w, "      signatures of the ancestors of block HASH are not verified; the default is set by the network, 0 verifies all")
+	fmt.Fprintln(w, "  migratechain - Rewrite a blockchain and wallet file created by an older release in the current format")
+	fmt.Fprintln(w, "  send [-from FROM] -to TO -amount AMOUNT [-strategy STRATEGY] - Send AMOUNT of coins from FROM address (or the whole wallet) to TO")
+	fmt.Fprintf(w, "      STRATEGY selects the coins to spend: %s (default largest)\n", strings.Join(tx.CoinSelectorNames(), ", "))
+}
+
+func (cli *CLI) validateArgs(args []string) bool {
+	return len(args) >= 1
+}
+
+// Run parses the command line, runs the command and returns the process
+// exit code
+func (cli *CLI) Run() int {
+	globalCmd := flag.NewFlagSet("tcn", flag.ContinueOnError)
+	globalCmd.Usage = cli.printUsage
+	logLevel := globalCmd.String("loglevel", "info", "Log level")
+	logFormat := globalCmd.String("logformat", logging.FormatText, "Log format, text or json")
+	globalCmd.BoolVar(&cli.json, "json", false, "Print results as JSON")
+	networkName := globalCmd.String("network", network.Main.Name, "Network, main or test")
+	pruneDepth := globalCmd.Int("prune", 0, "Number of recent blocks whose bodies are kept, 0 to keep all")
+
+	if err := globalCmd.Parse(os.Args[1:]); err != nil {
+		return exitUsage
+	}
+	args := globalCmd.Args()
+	if !cli.validateArgs(args) {
+		cli.printUsage()
+		return exitUsage
+	}
+
+	if err := configureLogging(*logLevel, *logFormat); err != nil {
+		cli.printError(err, exitUsage)
+		return exitUsage
+	}
+
+	params, err := network.ByName(*networkName)
+	if err != nil {
+		cli.printError(err, exitUsage)
+		return exitUsage
+	}
+	cli.params = params
+
+	globalCmd.Visit(func(f *flag.Flag) {
+		if f.Name == "prune" {
+			cli.prune = pruneDepth
+		}
+	})
+	if *pruneDepth < 0 || (*pruneDepth > 0 && *pruneDepth < chain.MinPruneDepth) {
+		cli.printError(fmt.Errorf("prune depth must be 0 or at least %d blocks", chain.MinPruneDepth), exitUsage)
+		return exitUsage
+	}
+
+	err = cli.run(args[0], args[1:])
+	if err != nil && err != errUsage {
+		cli.printError(err, exitCode(err))
+	}
+
+	return exitCode(err)
+}
+
+// Apply the -loglevel and -logformat flags
+func configureLogging(levels, format string) error {
+	level, subsystems, err := logging.ParseLevels(levels)
+	if err != nil {
+		return err
+	}
+
+	return logging.Configure(logging.Options{Level: level, Subsystems: subsystems, Format: format})
+}
+
+// Run command with the arguments following it
+func (cli *CLI) run(command string, args []string) error {
+	runCommand, ok := commands[command]
+	if !ok {
+		cli.printUsage()
+		return errUsage
+	}
+
+	return runCommand(cli, args)
+}
+
+func (cli *CLI) send(from, to string, amount tx.Amount, strategy string) error {
+	if from != "" && !wallet.ValidateAddress(from) {
+		return fmt.Errorf("sender %w", wallet.ErrInvalidAddress)
+	}
+	if !wallet.ValidateAddress(to) {
+		return fmt.Errorf("recipient %w", wallet.ErrInvalidAddress)
+	}
+	selector, err := tx.GetCoinSelector(strategy)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	bc, err := cli.openBlockchain()
+	if err != nil {
+		return err
+	}
+	defer bc.Close()
+
+	wallets, err := wallet.NewWallets(cli.walletOptions())
+	defer wallets.Close()
+	if err != nil {
+		return err
+	}
+
+	newTx, err := chain.NewUTXOTransaction(wallets, from, to, amount, selector, bc)
+	if err != nil {
+		return err
+	}
+	// the sender mines the block, or a fresh address when spending from
+	// the whole wallet
+	reward := from
+	if reward == "" {
+		if reward, err = wallets.CreateWallet(); err != nil {
+			return err
+		}
+	}
+	if err := bc.CheckFits(newTx, reward); err != nil {
+		return err
+	}
+	// keep the change and reward keys before their outputs can be mined
+	if err := wallets.SaveToFile(); err != nil {
+		return err
+	}
+	block, err := bc.MineBlock([]*tx.Transaction{newTx}, reward)
+	if err != nil {
+		return err
+	}
+
+	result := sendResult{hex.EncodeToString(newTx.ID), hex.EncodeToString(block.Hash)}
+
+	return cli.output(result, func() {
+		fmt.Println("Success! ")
+	})
+}
+
+func (cli *CLI) printChain() error {
+	bc, err := cli.openBlockchain()
+	if err != nil {
+		return err
+	}
+	defer bc.Close()
+
+	var blocks []*chain.Block
+	bci := bc.Iterator()
+
+	for {
+		block, err := bci.Next()
+		if err != nil {
+			return err
+		}
+		blocks = append(blocks, block)
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	return cli.output(blocksResult{blocks}, func() {
+		for _, block := range blocks {
+			fmt.Printf("========== Block %x ==========\n", block.Hash)
+			printHeader(&block.BlockHeader)
+			fmt.Println()
+			for _, tx := range block.Transactions {
+				fmt.Println(tx)
+			}
+			fmt.Printf("\n\n")
+		}
+	})
+}
+
+func (cli *CLI) printHeaders() error {
+	bc, err := cli.openBlockchain()
+	if err != nil {
+		return err
+	}
+	defer bc.Close()
+
+	headers := []headerResult{}
+	hi := bc.HeaderIterator()
+
+	for {
+		header, hash, err := hi.Next()
+		if err != nil {
+			return err
+		}
+		if header == nil {
+			break
+		}
+		headers = append(headers, headerResult{hex.EncodeToString(hash), header, header.CheckProofOfWork()})
+	}
+
+	return cli.output(headersResult{headers}, func() {
+		for _, header := range headers {
+			fmt.Printf("========== Block %s ==========\n", header.Hash)
+			printHeader(header.Header)
+			fmt.Printf("\n")
+		}
+	})
+}
+
+func printHeader(header *chain.BlockHeader) {
+	fmt.Printf("Height: %d\n", header.Height)
+	fmt.Printf("Version: %d\n", header.Version)
+	fmt.Printf("Prev Block: %x\n", header.PrevBlockHash)
+	fmt.Printf("Merkle Root: %x\n", header.MerkleRoot)
+	fmt.Printf("Timestamp: %d\n", header.Timestamp)
+	fmt.Printf("Bits: %d\n", header.Bits)
+	fmt.Printf("Nonce: %d\n", header.Nonce)
+	fmt.Printf("PoW: %s\n", strconv.FormatBool(header.CheckProofOfWork()))
+}
+
+func (cli *CLI) getBlock(blockHash string) error {
+	hash, err := hex.DecodeString(blockHash)
+	if err != nil {
+		return fmt.Errorf("%w: block hash is not valid hex", errUsage)
+	}
+
+	bc, err := cli.openBlockchain()
+	if err != nil {
+		return err
+	}
+	defer bc.Close()
+
+	block, err := bc.GetBlock(hash)
+	if err != nil {
+		return err
+	}
+	mtp, err := bc.MedianTimePast(hash)
+	if err != nil {
+		return err
+	}
+
+	return cli.output(blockResult{block, mtp}, func() {
+		fmt.Printf("========== Block %x ==========\n", block.Hash)
+		printHeader(&block.BlockHeader)
+		fmt.Printf("Median Time Past: %d\n\n", mtp)
+		for _, tx := range block.Transactions {
+			fmt.Println(tx)
+		}
+	})
+}
+
+func (cli *CLI) verifyHeaders() error {
+	bc, err := cli.openBlockchain()
+	if err != nil {
+		return err
+	}
+	defer bc.Close()
+
+	count, err := bc.ValidateHeaders()
+	if err != nil {
+		return fmt.Errorf("invalid header chain: %w", err)
+	}
+
+	return cli.output(verifyHeadersResult{count}, func() {
+		fmt.Printf("Verified %d headers\n", count)
+	})
+}
+
+func (cli *CLI) getChainInfo() error {
+	bc, err := cli.openBlockchain()
+	if err != nil {
+		return err
+	}
+	defer bc.Close()
+
+	tip, err := bc.GetHeader(bc.Tip())
+	if err != nil {
+		return err
+	}
+	info, err := bc.PruneInfo()
+	if err != nil {
+		return err
+	}
+
+	result := chainInfoResult{tip.Height, hex.EncodeToString(bc.Tip()), info.Depth, info.PrunedHeight, info.FreedBytes}
+
+	return cli.output(result, func() {
+		fmt.Printf("Height: %d\n", result.Height)
+		fmt.Printf("Tip: %s\n", result.Tip)
+		if info.Depth == 0 {
+			fmt.Println("Pruning: off")
+		} else {
+			fmt.Printf("Pruning: keeping the last %d blocks\n", info.Depth)
+		}
+		if info.PrunedHeight > 0 {
+			fmt.Printf("Pruned: blocks below height %d, %d bytes freed inside the database\n", info.PrunedHeight, info.FreedBytes)
+		}
+	})
+}
+
+func (cli *CLI) invalidateBlock(blockHash string) error {
+	hash, err := hex.DecodeString(blockHash)
+	if err != nil {
+		return fmt.Errorf("%w: block hash is not valid hex", errUsage)
+	}
+
+	bc, err := cli.openBlockchain()
+	if err != nil {
+		return err
+	}
+	defer bc.Close()
+
+	disconnected, err := bc.InvalidateBlock(hash)
+	if err != nil {
+		return err
+	}
+
+	result := invalidateResult{disconnected, hex.EncodeToString(bc.Tip())}
+
+	return cli.output(result, func() {
+		fmt.Printf("Disconnected %d blocks, the tip is now %s\n", disconnected, result.Tip)
+	})
+}
+
+func (cli *CLI) reconsiderBlock(blockHash string) error {
+	hash, err := hex.DecodeString(blockHash)
+	if err != nil {
+		return fmt.Errorf("%w: block hash is not valid hex", errUsage)
+	}
+
+	bc, err := cli.openBlockchain()
+	if err != nil {
+		return err
+	}
+	defer bc.Close()
+
+	connected, err := bc.ReconsiderBlock(hash)
+	if err != nil {
+		return err
+	}
+
+	result := reconsiderResult{connected, hex.EncodeToString(bc.Tip())}
+
+	return cli.output(result, func() {
+		fmt.Printf("Connected %d blocks, the tip is now %s\n", connected, result.Tip)
+	})
+}
+
+func (cli *CLI) listAddresses() error {
+	wallets, err := wallet.NewWallets(cli.walletOptions())
+	defer wallets.Close()
+	if err != nil {
+		return err
+	}
+
+	// balances are only shown once a blockchain exists
+	balances := make(map[string]tx.Amount)
+	bc, err := cli.openBlockchain()
+	if err != nil && !errors.Is(err, chain.ErrChainNotFound) {
+		return err
+	}
+	if err == nil {
+		defer bc.Close()
+
+		utxos, _, err := findWalletUTXOs(bc, wallets)
+		if err != nil {
+			return err
+		}
+		for _, utxo := range utxos {
+			balance, err := balances[utxo.Address].Add(utxo.Output.Value)
+			if err != nil {
+				return err
+			}
+			balances[utxo.Address] = balance
+		}
+	}
+
+	result := addressesResult{Addresses: []addressEntry{}}
+	for _, address := range walletAddresses(wallets) {
+		entry := addressEntry{Address: address, Label: wallets.GetLabel(address), WatchOnly: wallets.IsWatchOnly(address)}
+		if bc != nil {
+			balance := balances[address]
+			entry.Balance = &balance
+		}
+		result.Addresses = append(result.Addresses, entry)
+	}
+
+	return cli.output(result, func() {
+		for _, entry := range result.Addresses {
+			line := entry.Address
+			if entry.Balance != nil {
+				line += fmt.Sprintf("  %s", *entry.Balance)
+			}
+			if entry.Label != "" {
+				line += fmt.Sprintf("  %q", entry.Label)
+			}
+			if entry.WatchOnly {
+				line += "  (watch-only)"
+			}
+			fmt.Println(line)
+		}
+	})
+}
+
+func (cli *CLI) setLabel(address, label string) error {
+	wallets, err := wallet.NewWallets(cli.walletOptions())
+	defer wallets.Close()
+	if err != nil {
+		return err
+	}
+	if err := wallets.SetLabel(address, label); err != nil {
+		return err
+	}
+	if err := wallets.SaveToFile(); err != nil {
+		return err
+	}
+
+	return cli.output(labelResult{address, label}, func() {
+		if label == "" {
+			fmt.Printf("Removed the label of %s\n", address)
+		} else {
+			fmt.Printf("Labelled %s %q\n", address, label)
+		}
+	})
+}
+
+func (cli *CLI) getWalletBalance(minConf int) error {
+	wallets, err := wallet.NewWallets(cli.walletOptions())
+	defer wallets.Close()
+	if err != nil {
+		return err
+	}
+	bc, err := cli.openBlockchain()
+	if err != nil {
+		return err
+	}
+	defer bc.Close()
+
+	utxos, height, err := findWalletUTXOs(bc, wallets)
+	if err != nil {
+		return err
+	}
+
+	result := walletBalanceResult{MinConf: minConf, Height: height}
+	for _, utxo := range utxos {
+		total := &result.Confirmed
+		switch {
+		case utxo.WatchOnly && utxo.Confirmations >= minConf:
+			total = &result.WatchOnlyConfirmed
+		case utxo.WatchOnly:
+			total = &result.WatchOnlyUnconfirmed
+		case utxo.Confirmations < minConf:
+			total = &result.Unconfirmed
+		}
+		if *total, err = total.Add(utxo.Output.Value); err != nil {
+			return err
+		}
+	}
+
+	return cli.output(result, func() {
+		fmt.Printf("Confirmed: %s\n", result.Confirmed)
+		fmt.Printf("Unconfirmed (fewer than %d confirmations): %s\n", minConf, result.Unconfirmed)
+		if len(wallets.WatchOnly) > 0 {
+			fmt.Printf("Watch-only confirmed: %s\n", result.WatchOnlyConfirmed)
+			fmt.Printf("Watch-only unconfirmed: %s\n", result.WatchOnlyUnconfirmed)
+		}
+	})
+}
+
+func (cli *CLI) listUnspent(minConf int) error {
+	wallets, err := wallet.NewWallets(cli.walletOptions())
+	defer wallets.Close()
+	if err != nil {
+		return err
+	}
+	bc, err := cli.openBlockchain()
+	if err != nil {
+		return err
+	}
+	defer bc.Close()
+
+	utxos, _, err := findWalletUTXOs(bc, wallets)
+	if err != nil {
+		return err
+	}
+
+	result := listUnspentResult{Unspent: []unspentResult{}}
+	for _, utxo := range utxos {
+		if utxo.Confirmations < minConf {
+			continue
+		}
+		result.Unspent = append(result.Unspent, unspentResult{
+			Txid:          hex.EncodeToString(utxo.TxID),
+			Vout:          utxo.Index,
+			Address:       utxo.Address,
+			Label:         wallets.GetLabel(utxo.Address),
+			Amount:        utxo.Output.Value,
+			Confirmations: utxo.Confirmations,
+			WatchOnly:     utxo.WatchOnly,
+		})
+	}
+
+	return cli.output(result, func() {
+		for _, unspent := range result.Unspent {
+			line := fmt.Sprintf("%s:%d  %s  %d confirmations  %s", unspent.Txid, unspent.Vout, unspent.Amount, unspent.Confirmations, unspent.Address)
+			if unspent.Label != "" {
+				line += fmt.Sprintf("  %q", unspent.Label)
+			}
+			if unspent.WatchOnly {
+				line += "  (watch-only)"
+			}
+			fmt.Println(line)
+		}
+	})
+}
+
+// Every address of the wallet file, the spendable ones first, each group
+// sorted
+func walletAddresses(wallets *wallet.Wallets) []string {
+	addresses := wallets.GetAddresses()
+	sort.Strings(addresses)
+	watchOnly := wallets.GetWatchOnlyAddresses()
+	sort.Strings(watchOnly)
+
+	return append(addresses, watchOnly...)
+}
+
+// An unspent output locked to an address of the wallet file
+type walletUTXO struct {
+	tx.UTXO
+	Address       string
+	WatchOnly     bool
+	Confirmations int
+}
+
+// Collect the unspent outputs locked to the addresses of the wallet file,
+// oldest first, and return them with the height of the chain
+func findWalletUTXOs(bc *chain.Blockchain, wallets *wallet.Wallets) ([]walletUTXO, int, error) {
+	tip, err := bc.GetHeader(bc.Tip())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	owners := make(map[string]string)
+	var pubKeyHashes [][]byte
+	for _, address := range walletAddresses(wallets) {
+		pubKeyHash, err := wallet.DecodeAddress(address)
+		if err != nil {
+			return nil, 0, err
+		}
+		owners[string(pubKeyHash)] = address
+		pubKeyHashes = append(pubKeyHashes, pubKeyHash)
+	}
+	if len(pubKeyHashes) == 0 {
+		return nil, tip.Height, nil
+	}
+
+	utxos, err := bc.FindUTXOs(pubKeyHashes)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var result []walletUTXO
+	for _, utxo := range utxos {
+		address := owners[string(utxo.Output.PubKeyHash)]
+		result = append(result, walletUTXO{
+			UTXO:          utxo,
+			Address:       address,
+			WatchOnly:     wallets.IsWatchOnly(address),
+			Confirmations: tip.Height - utxo.Height + 1,
+		})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Height < result[j].Height
+	})
+
+	return result, tip.Height, nil
+}
+
+func (cli *CLI) createWallet(label string, schnorr bool) error {
+	wallets, err := wallet.NewWallets(cli.walletOptions())
+	defer wallets.Close()
+	if err != nil && !errors.Is(err, wallet.ErrWalletFileNotFound) {
+		return err
+	}
+	create := wallets.CreateWallet
+	if schnorr {
+		create = wallets.CreateSchnorrWallet
+	}
+	address, err := create()
+	if err != nil {
+		return err
+	}
+	if err := wallets.SetLabel(address, label); err != nil {
+		return err
+	}
+	if err := wallets.SaveToFile(); err != nil {
+		return err
+	}
+
+	return cli.output(addressResult{address}, func() {
+		fmt.Printf("Your new address: %s\n", address)
+	})
+}
+
+func (cli *CLI) vanityAddress(prefix string, workers int, schnorr bool, label string) error {
+	difficulty, err := wallet.VanityDifficulty(prefix, schnorr)
+	if err != nil {
+		return err
+	}
+	// the wallet file is opened once the key is found, so other commands
+	// can use it during the search
+	w, tried, err := wallet.FindVanityWallet(prefix, schnorr, workers)
+	if err != nil {
+		return err
+	}
+	wallets, err := wallet.NewWallets(cli.walletOptions())
+	defer wallets.Close()
+	if err != nil && !errors.Is(err, wallet.ErrWalletFileNotFound) {
+		return err
+	}
+	address, _ := wallets.ImportWallet(w)
+	if err := wallets.SetLabel(address, label); err != nil {
+		return err
+	}
+	if err := wallets.SaveToFile(); err != nil {
+		return err
+	}
+
+	return cli.output(vanityResult{address, prefix, tried, difficulty}, func() {
+		fmt.Printf("Your new address: %s\n", address)
+		fmt.Printf("Found after %d keys, %.0f expected\n", tried, difficulty)
+	})
+}
+
+func (cli *CLI) backupWallet(file string) error {
+	wallets, err := wallet.NewWallets(cli.walletOptions())
+	defer wallets.Close()
+	if err != nil {
+		return err
+	}
+	if err := wallets.Backup(file); err != nil {
+		return err
+	}
+	addresses := len(wallets.Wallets) + len(wallets.WatchOnly)
+
+	return cli.output(backupResult{file, addresses}, func() {
+		fmt.Printf("Backed up %d addresses to %s\n", addresses, file)
+	})
+}
+
+func (cli *CLI) dumpPrivKey(address string) error {
+	wallets, err := wallet.NewWallets(cli.walletOptions())
+	defer wallets.Close()
+	if err != nil {
+		return err
+	}
+	w, err := wallets.GetWallet(address)
+	if err != nil {
+		return err
+	}
+	key := w.ExportPrivateKey(cli.params)
+
+	return cli.output(privateKeyResult{address, key}, func() {
+		fmt.Println(key)
+	})
+}
+
+func (cli *CLI) importPrivKey(key string, rescan bool) error {
+	w, err := wallet.ImportPrivateKey(key, cli.params)
+	if err != nil {
+		return err
+	}
+
+	wallets, err := wallet.NewWallets(cli.walletOptions())
+	defer wallets.Close()
+	if err != nil && !errors.Is(err, wallet.ErrWalletFileNotFound) {
+		return err
+	}
+	address, added := wallets.ImportWallet(w)
+	if added {
+		if err := wallets.SaveToFile(); err != nil {
+			return err
+		}
+	}
+
+	result := importKeyResult{Address: address, Added: added}
+	if rescan {
+		bc, err := cli.openBlockchain()
+		if err != nil {
+			return err
+		}
+		defer bc.Close()
+
+		balance, err := balanceOf(bc, wallet.HashPubKey(w.PublicKey))
+		if err != nil {
+			return err
+		}
+		result.Balance = &balance
+	}
+
+	return cli.output(result, func() {
+		if added {
+			fmt.Printf("Imported address: %s\n", address)
+		} else {
+			fmt.Printf("Address %s is already in the wallet file\n", address)
+		}
+		if result.Balance != nil {
+			fmt.Printf("Balance of '%s': %s\n", address, *result.Balance)
+		}
+	})
+}
+
+func (cli *CLI) signMessage(address, message string) error {
+	wallets, err := wallet.NewWallets(cli.walletOptions())
+	defer wallets.Close()
+	if err != nil {
+		return err
+	}
+	w, err := wallets.GetWallet(address)
+	if err != nil {
+		return err
+	}
+	signature, err := w.SignMessage(message)
+	if err != nil {
+		return err
+	}
+
+	return cli.output(signatureResult{address, signature}, func() {
+		fmt.Println(signature)
+	})
+}
+
+func (cli *CLI) verifyMessage(address, signature, message string) error {
+	if err := wallet.VerifyMessage(address, signature, message); err != nil {
+		return err
+	}
+
+	return cli.output(verifyMessageResult{address, true}, func() {
+		fmt.Printf("The message was signed by %s\n", address)
+	})
+}
+
+func (cli *CLI) importAddress(address string, rescan bool) error {
+	wallets, err := wallet.NewWallets(cli.walletOptions())
+	defer wallets.Close()
+	if err != nil && !errors.Is(err, wallet.ErrWalletFileNotFound) {
+		return err
+	}
+	added, err := wallets.ImportAddress(address)
+	if err != nil {
+		return err
+	}
+	if added {
+		if err := wallets.SaveToFile(); err != nil {
+			return err
+		}
+	}
+
+	return cli.outputWatchOnly(wallets, address, added, rescan)
+}
+
+func (cli *CLI) importPubKey(pubKeyHex string, rescan bool) error {
+	pubKey, err := hex.DecodeString(pubKeyHex)
+	if err != nil {
+		return fmt.Errorf("%w: %v", wallet.ErrInvalidPublicKey, err)
+	}
+
+	wallets, err := wallet.NewWallets(cli.walletOptions())
+	defer wallets.Close()
+	if err != nil && !errors.Is(err, wallet.ErrWalletFileNotFound) {
+		return err
+	}
+	address, added, err := wallets.ImportPubKey(pubKey)
+	if err != nil {
+		return err
+	}
+	// an address already watched gains its public key, so save either way
+	if err := wallets.SaveToFile(); err != nil {
+		return err
+	}
+
+	return cli.outputWatchOnly(wallets, address, added, rescan)
+}
+
+func (cli *CLI) getPubKey(address string) error {
+	wallets, err := wallet.NewWallets(cli.walletOptions())
+	defer wallets.Close()
+	if err != nil {
+		return err
+	}
+	w, err := wallets.GetWallet(address)
+	if err != nil {
+		return err
+	}
+	pubKey := hex.EncodeToString(w.PublicKey)
+
+	return cli.output(pubKeyResult{address, pubKey, w.IsSchnorr()}, func() {
+		fmt.Println(pubKey)
+	})
+}
+
+func (cli *CLI) aggregateKeys(pubKeysHex []string) error {
+	var pubKeys [][]byte
+	for _, pubKeyHex := range pubKeysHex {
+		pubKey, err := hex.DecodeString(strings.TrimSpace(pubKeyHex))
+		if err != nil {
+			return fmt.Errorf("%w: %v", wallet.ErrInvalidPublicKey, err)
+		}
+		pubKeys = append(pubKeys, pubKey)
+	}
+
+	aggregate, err := ecc.AggregateKeys(pubKeys)
+	if err != nil {
+		return fmt.Errorf("%w: %v", wallet.ErrInvalidPublicKey, err)
+	}
+	result := pubKeyResult{string(wallet.PubKeyAddress(aggregate)), hex.EncodeToString(aggregate), true}
+
+	return cli.output(result, func() {
+		fmt.Printf("Aggregate key: %s\n", result.PubKey)
+		fmt.Printf("Address: %s\n", result.Address)
+	})
+}
+
+// Print the result of importaddress or importpubkey, with the balance of
+// the address if rescan is set
+func (cli *CLI) outputWatchOnly(wallets *wallet.Wallets, address string, added, rescan bool) error {
+	result := importWatchOnlyResult{Address: address, Added: added, WatchOnly: wallets.IsWatchOnly(address)}
+	if rescan {
+		pubKeyHash, err := wallet.DecodeAddress(address)
+		if err != nil {
+			return err
+		}
+		bc, err := cli.openBlockchain()
+		if err != nil {
+			return err
+		}
+		defer bc.Close()
+
+		balance, err := balanceOf(bc, pubKeyHash)
+		if err != nil {
+			return err
+		}
+		result.Balance = &balance
+	}
+
+	return cli.output(result, func() {
+		switch {
+		case added:
+			fmt.Printf("Imported watch-only address: %s\n", address)
+		case result.WatchOnly:
+			fmt.Printf("Address %s is already watched\n", address)
+		default:
+			fmt.Printf("Address %s is already in the wallet file with its private key\n", address)
+		}
+		if result.Balance != nil {
+			fmt.Printf("Balance of '%s': %s\n", address, *result.Balance)
+		}
+	})
+}
+
+func (cli *CLI) migrateChain() error {
+	blocks, err := chain.MigrateChain(cli.chainOptions())
+	if err != nil {
+		return err
+	}
+
+	migrated, err := wallet.Migrate(cli.walletOptions())
+	if err != nil {
+		return err
+	}
+
+	return cli.output(migrateResult{blocks, migrated}, func() {
+		fmt.Printf("Migrated %d blocks\n", blocks)
+		if migrated {
+			fmt.Println("Migrated wallet file")
+		}
+	})
+}
+
+func (cli *CLI) exportChain(file string, from, to int) error {
+	bc, err := cli.openBlockchain()
+	if err != nil {
+		return err
+	}
+	defer bc.Close()
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+
+	blocks, err := bc.ExportChain(f, from, to)
+	if err != nil {
+		f.Close()
+		os.Remove(file)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return cli.output(bootstrapResult{file, blocks}, func() {
+		fmt.Printf("Exported %d blocks to %s\n", blocks, file)
+	})
+}
+
+func (cli *CLI) importChain(file, assumeValidHash string) error {
+	var assumeValid []byte
+	switch assumeValidHash {
+	case "":
+		assumeValid = cli.params.AssumeValid
+	case "0":
+	default:
+		var err error
+		assumeValid, err = hex.DecodeString(assumeValidHash)
+		if err != nil {
+			return fmt.Errorf("%w: assumed valid block hash is not valid hex", errUsage)
+		}
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	bc, blocks, err := chain.ImportChain(cli.chainOptions(), f, assumeValid)
+	if err != nil {
+		return err
+	}
+	defer bc.Close()
+	if err := cli.applyPrune(bc); err != nil {
+		return err
+	}
+
+	return cli.output(bootstrapResult{file, blocks}, func() {
+		fmt.Printf("Imported %d blocks from %s\n", blocks, file)
+	})
+}
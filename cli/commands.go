@@ -0,0 +1,408 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/jplesperance/tcn/tx"
+)
+
+// Each command parses its own flags and runs the CLI method doing the work
+var commands = map[string]func(cli *CLI, args []string) error{
+	"getbalance":       (*CLI).runGetBalance,
+	"createblockchain": (*CLI).runCreateBlockchain,
+	"printchain":       (*CLI).runPrintChain,
+	"getblock":         (*CLI).runGetBlock,
+	"verifyheaders":    (*CLI).runVerifyHeaders,
+	"getchaininfo":     (*CLI).runGetChainInfo,
+	"backupwallet":     (*CLI).runBackupWallet,
+	"dumpprivkey":      (*CLI).runDumpPrivKey,
+	"importprivkey":    (*CLI).runImportPrivKey,
+	"signmessage":      (*CLI).runSignMessage,
+	"verifymessage":    (*CLI).runVerifyMessage,
+	"importaddress":    (*CLI).runImportAddress,
+	"importpubkey":     (*CLI).runImportPubKey,
+	"getpubkey":        (*CLI).runGetPubKey,
+	"aggregatekeys":    (*CLI).runAggregateKeys,
+	"invalidateblock":  (*CLI).runInvalidateBlock,
+	"reconsiderblock":  (*CLI).runReconsiderBlock,
+	"send":             (*CLI).runSend,
+	"createwallet":     (*CLI).runCreateWallet,
+	"vanityaddress":    (*CLI).runVanityAddress,
+	"setlabel":         (*CLI).runSetLabel,
+	"getwalletbalance": (*CLI).runGetWalletBalance,
+	"listunspent":      (*CLI).runListUnspent,
+	"listaddresses":    (*CLI).runListAddresses,
+	"migratechain":     (*CLI).runMigrateChain,
+	"exportchain":      (*CLI).runExportChain,
+	"importchain":      (*CLI).runImportChain,
+}
+
+// Return the flag set of command.  Parse errors are returned rather than
+// exiting, as with the global flags.
+func newFlagSet(command string) *flag.FlagSet {
+	return flag.NewFlagSet(command, flag.ContinueOnError)
+}
+
+// Print the usage of fs and return errUsage
+func usage(fs *flag.FlagSet) error {
+	fs.Usage()
+	return errUsage
+}
+
+func (cli *CLI) runGetBalance(args []string) error {
+	fs := newFlagSet("getbalance")
+	address := fs.String("address", "", "The address to get balance for")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *address == "" {
+		return usage(fs)
+	}
+
+	return cli.getBalance(*address)
+}
+
+func (cli *CLI) runCreateBlockchain(args []string) error {
+	fs := newFlagSet("createblockchain")
+	address := fs.String("address", "", "The address to send genesis block reward to")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *address == "" {
+		return usage(fs)
+	}
+
+	return cli.createBlockchain(*address)
+}
+
+func (cli *CLI) runPrintChain(args []string) error {
+	fs := newFlagSet("printchain")
+	headers := fs.Bool("headers", false, "Print block headers only")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *headers {
+		return cli.printHeaders()
+	}
+
+	return cli.printChain()
+}
+
+func (cli *CLI) runGetBlock(args []string) error {
+	fs := newFlagSet("getblock")
+	hash := fs.String("hash", "", "Hash of the block to print")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *hash == "" {
+		return usage(fs)
+	}
+
+	return cli.getBlock(*hash)
+}
+
+func (cli *CLI) runVerifyHeaders(args []string) error {
+	fs := newFlagSet("verifyheaders")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	return cli.verifyHeaders()
+}
+
+func (cli *CLI) runGetChainInfo(args []string) error {
+	fs := newFlagSet("getchaininfo")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	return cli.getChainInfo()
+}
+
+func (cli *CLI) runBackupWallet(args []string) error {
+	fs := newFlagSet("backupwallet")
+	file := fs.String("file", "", "The file to copy the wallet file to")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *file == "" {
+		return usage(fs)
+	}
+
+	return cli.backupWallet(*file)
+}
+
+func (cli *CLI) runDumpPrivKey(args []string) error {
+	fs := newFlagSet("dumpprivkey")
+	address := fs.String("address", "", "The address whose private key to print")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *address == "" {
+		return usage(fs)
+	}
+
+	return cli.dumpPrivKey(*address)
+}
+
+func (cli *CLI) runImportPrivKey(args []string) error {
+	fs := newFlagSet("importprivkey")
+	key := fs.String("key", "", "The private key to import")
+	rescan := fs.Bool("rescan", false, "Report the balance of the key found in the chain")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *key == "" {
+		return usage(fs)
+	}
+
+	return cli.importPrivKey(*key, *rescan)
+}
+
+func (cli *CLI) runSignMessage(args []string) error {
+	fs := newFlagSet("signmessage")
+	address := fs.String("address", "", "The address whose key signs the message")
+	message := fs.String("message", "", "The message to sign")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *address == "" {
+		return usage(fs)
+	}
+
+	return cli.signMessage(*address, *message)
+}
+
+func (cli *CLI) runVerifyMessage(args []string) error {
+	fs := newFlagSet("verifymessage")
+	address := fs.String("address", "", "The address that signed the message")
+	signature := fs.String("signature", "", "The signature printed by signmessage")
+	message := fs.String("message", "", "The signed message")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *address == "" || *signature == "" {
+		return usage(fs)
+	}
+
+	return cli.verifyMessage(*address, *signature, *message)
+}
+
+func (cli *CLI) runImportAddress(args []string) error {
+	fs := newFlagSet("importaddress")
+	address := fs.String("address", "", "The address to watch")
+	rescan := fs.Bool("rescan", false, "Report the balance of the address found in the chain")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *address == "" {
+		return usage(fs)
+	}
+
+	return cli.importAddress(*address, *rescan)
+}
+
+func (cli *CLI) runImportPubKey(args []string) error {
+	fs := newFlagSet("importpubkey")
+	pubKey := fs.String("pubkey", "", "Hex encoded public key whose address to watch")
+	rescan := fs.Bool("rescan", false, "Report the balance of the key found in the chain")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *pubKey == "" {
+		return usage(fs)
+	}
+
+	return cli.importPubKey(*pubKey, *rescan)
+}
+
+func (cli *CLI) runGetPubKey(args []string) error {
+	fs := newFlagSet("getpubkey")
+	address := fs.String("address", "", "The address whose public key to print")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *address == "" {
+		return usage(fs)
+	}
+
+	return cli.getPubKey(*address)
+}
+
+func (cli *CLI) runAggregateKeys(args []string) error {
+	fs := newFlagSet("aggregatekeys")
+	pubKeys := fs.String("pubkeys", "", "Comma separated hex encoded Schnorr public keys")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *pubKeys == "" {
+		return usage(fs)
+	}
+
+	return cli.aggregateKeys(strings.Split(*pubKeys, ","))
+}
+
+func (cli *CLI) runInvalidateBlock(args []string) error {
+	fs := newFlagSet("invalidateblock")
+	hash := fs.String("hash", "", "Hash of the block to invalidate")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *hash == "" {
+		return usage(fs)
+	}
+
+	return cli.invalidateBlock(*hash)
+}
+
+func (cli *CLI) runReconsiderBlock(args []string) error {
+	fs := newFlagSet("reconsiderblock")
+	hash := fs.String("hash", "", "Hash of the block to reconsider")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *hash == "" {
+		return usage(fs)
+	}
+
+	return cli.reconsiderBlock(*hash)
+}
+
+func (cli *CLI) runSend(args []string) error {
+	fs := newFlagSet("send")
+	from := fs.String("from", "", "Source wallet address, spends from all wallet addresses if omitted")
+	to := fs.String("to", "", "Destination wallet address")
+	amountText := fs.String("amount", "", "Amount of coins to send, e.g. 0.5")
+	strategy := fs.String("strategy", "largest", "Coin selection strategy")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *to == "" || *amountText == "" {
+		return usage(fs)
+	}
+	amount, err := tx.ParseAmount(*amountText)
+	if err != nil || amount == 0 {
+		fmt.Fprintln(os.Stderr, "Error: amount must be a positive number of coins")
+		return usage(fs)
+	}
+
+	return cli.send(*from, *to, amount, *strategy)
+}
+
+func (cli *CLI) runCreateWallet(args []string) error {
+	fs := newFlagSet("createwallet")
+	label := fs.String("label", "", "Label of the new address")
+	schnorr := fs.Bool("schnorr", false, "Create a key for Schnorr signatures")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	return cli.createWallet(*label, *schnorr)
+}
+
+func (cli *CLI) runVanityAddress(args []string) error {
+	fs := newFlagSet("vanityaddress")
+	prefix := fs.String("prefix", "", "Base58 prefix the address must start with, e.g. 1TCN")
+	workers := fs.Int("workers", runtime.NumCPU(), "Number of keys generated in parallel")
+	schnorr := fs.Bool("schnorr", false, "Search for a Schnorr address, starting with S")
+	label := fs.String("label", "", "Label of the new address")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *prefix == "" || *workers < 1 {
+		return usage(fs)
+	}
+
+	return cli.vanityAddress(*prefix, *workers, *schnorr, *label)
+}
+
+func (cli *CLI) runSetLabel(args []string) error {
+	fs := newFlagSet("setlabel")
+	address := fs.String("address", "", "The address to label")
+	label := fs.String("label", "", "The label, empty to remove it")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *address == "" {
+		return usage(fs)
+	}
+
+	return cli.setLabel(*address, *label)
+}
+
+func (cli *CLI) runGetWalletBalance(args []string) error {
+	fs := newFlagSet("getwalletbalance")
+	minConf := fs.Int("minconf", defaultMinConf, "Confirmations for an output to count as confirmed")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *minConf < 1 {
+		return usage(fs)
+	}
+
+	return cli.getWalletBalance(*minConf)
+}
+
+func (cli *CLI) runListUnspent(args []string) error {
+	fs := newFlagSet("listunspent")
+	minConf := fs.Int("minconf", 1, "Only list outputs with at least this many confirmations")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *minConf < 1 {
+		return usage(fs)
+	}
+
+	return cli.listUnspent(*minConf)
+}
+
+func (cli *CLI) runListAddresses(args []string) error {
+	fs := newFlagSet("listaddresses")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	return cli.listAddresses()
+}
+
+func (cli *CLI) runMigrateChain(args []string) error {
+	fs := newFlagSet("migratechain")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	return cli.migrateChain()
+}
+
+func (cli *CLI) runExportChain(args []string) error {
+	fs := newFlagSet("exportchain")
+	file := fs.String("file", "", "Bootstrap file to write")
+	from := fs.Int("from", 0, "Height of the first block to export")
+	to := fs.Int("to", -1, "Height of the last block to export, the tip if negative")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *file == "" {
+		return usage(fs)
+	}
+
+	return cli.exportChain(*file, *from, *to)
+}
+
+func (cli *CLI) runImportChain(args []string) error {
+	fs := newFlagSet("importchain")
+	file := fs.String("file", "", "Bootstrap file to read")
+	assumeValid := fs.String("assumevalid", "", "Hash of a block whose ancestors' signatures are not verified, 0 to verify all")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *file == "" {
+		return usage(fs)
+	}
+
+	return cli.importChain(*file, *assumeValid)
+}
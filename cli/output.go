@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jplesperance/tcn/chain"
+	"github.com/jplesperance/tcn/tx"
+)
+
+// Results printed by the commands when -json is given.  Field names are
+// part of the stable output schema.
+
+type balanceResult struct {
+	Address   string    `json:"address"`
+	Balance   tx.Amount `json:"balance"`
+	WatchOnly bool      `json:"watch_only,omitempty"`
+}
+
+type addressResult struct {
+	Address string `json:"address"`
+}
+
+type vanityResult struct {
+	Address    string  `json:"address"`
+	Prefix     string  `json:"prefix"`
+	Tried      uint64  `json:"tried"`
+	Difficulty float64 `json:"difficulty"`
+}
+
+type addressEntry struct {
+	Address   string     `json:"address"`
+	Label     string     `json:"label"`
+	Balance   *tx.Amount `json:"balance,omitempty"`
+	WatchOnly bool       `json:"watch_only"`
+}
+
+type addressesResult struct {
+	Addresses []addressEntry `json:"addresses"`
+}
+
+type labelResult struct {
+	Address string `json:"address"`
+	Label   string `json:"label"`
+}
+
+type walletBalanceResult struct {
+	Confirmed            tx.Amount `json:"confirmed"`
+	Unconfirmed          tx.Amount `json:"unconfirmed"`
+	WatchOnlyConfirmed   tx.Amount `json:"watch_only_confirmed"`
+	WatchOnlyUnconfirmed tx.Amount `json:"watch_only_unconfirmed"`
+	MinConf              int       `json:"minconf"`
+	Height               int       `json:"height"`
+}
+
+type unspentResult struct {
+	Txid          string    `json:"txid"`
+	Vout          int       `json:"vout"`
+	Address       string    `json:"address"`
+	Label         string    `json:"label"`
+	Amount        tx.Amount `json:"amount"`
+	Confirmations int       `json:"confirmations"`
+	WatchOnly     bool      `json:"watch_only"`
+}
+
+type listUnspentResult struct {
+	Unspent []unspentResult `json:"unspent"`
+}
+
+type backupResult struct {
+	File      string `json:"file"`
+	Addresses int    `json:"addresses"`
+}
+
+type privateKeyResult struct {
+	Address string `json:"address"`
+	Key     string `json:"key"`
+}
+
+type pubKeyResult struct {
+	Address string `json:"address"`
+	PubKey  string `json:"pubkey"`
+	Schnorr bool   `json:"schnorr"`
+}
+
+type signatureResult struct {
+	Address   string `json:"address"`
+	Signature string `json:"signature"`
+}
+
+type verifyMessageResult struct {
+	Address string `json:"address"`
+	Valid   bool   `json:"valid"`
+}
+
+type importKeyResult struct {
+	Address string     `json:"address"`
+	Added   bool       `json:"added"`
+	Balance *tx.Amount `json:"balance,omitempty"`
+}
+
+type importWatchOnlyResult struct {
+	Address   string     `json:"address"`
+	Added     bool       `json:"added"`
+	WatchOnly bool       `json:"watch_only"`
+	Balance   *tx.Amount `json:"balance,omitempty"`
+}
+
+type createBlockchainResult struct {
+	Genesis string `json:"genesis"`
+	Tip     string `json:"tip"`
+}
+
+type sendResult struct {
+	Txid  string `json:"txid"`
+	Block string `json:"block"`
+}
+
+type blocksResult struct {
+	Blocks []*chain.Block `json:"blocks"`
+}
+
+type headerResult struct {
+	Hash     string             `json:"hash"`
+	Header   *chain.BlockHeader `json:"header"`
+	PoWValid bool               `json:"pow_valid"`
+}
+
+type headersResult struct {
+	Headers []headerResult `json:"headers"`
+}
+
+type blockResult struct {
+	Block          *chain.Block `json:"block"`
+	MedianTimePast int64        `json:"median_time_past"`
+}
+
+type verifyHeadersResult struct {
+	Verified int `json:"verified"`
+}
+
+type chainInfoResult struct {
+	Height       int    `json:"height"`
+	Tip          string `json:"tip"`
+	PruneDepth   int    `json:"prune_depth"`
+	PrunedHeight int    `json:"pruned_height"`
+	FreedBytes   int64  `json:"freed_bytes"`
+}
+
+type invalidateResult struct {
+	Disconnected int    `json:"disconnected"`
+	Tip          string `json:"tip"`
+}
+
+type reconsiderResult struct {
+	Connected int    `json:"connected"`
+	Tip       string `json:"tip"`
+}
+
+type migrateResult struct {
+	Blocks         int  `json:"blocks"`
+	WalletMigrated bool `json:"wallet_migrated"`
+}
+
+type bootstrapResult struct {
+	File   string `json:"file"`
+	Blocks int    `json:"blocks"`
+}
+
+type errorResult struct {
+	Error string `json:"error"`
+	Code  int    `json:"code"`
+}
+
+// Write a command result to stdout, as JSON when -json was given and
+// otherwise with the text printer
+func (cli *CLI) output(result interface{}, text func()) error {
+	if !cli.json {
+		text()
+		return nil
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(result)
+}
+
+// Report a failed command on stderr
+func (cli *CLI) printError(err error, code int) {
+	if !cli.json {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return
+	}
+
+	encoder := json.NewEncoder(os.Stderr)
+	encoder.Encode(errorResult{err.Error(), code})
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"os"
+
+	"github.com/jplesperance/tcn/cli"
+)
+
+// Meat of the app
+func main() {
+
+	c := cli.CLI{}
+	os.Exit(c.Run())
+}
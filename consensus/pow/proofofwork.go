@@ -0,0 +1,100 @@
+// Package pow implements the proof of work consensus rule
+package pow
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/jplesperance/tcn/logging"
+)
+
+var log = logging.New(logging.PoW)
+
+// number of hashes between progress records
+const progressInterval = 1 << 20
+
+// define the difficulty of mining
+// Picked an arbitrary number, the goal is to have a target less than 256 bits in memory
+
+// Todo: Implement a difficulty adjusting algorithm
+const TargetBits = 21
+
+// define the maximum value of nonce
+var MaxNonce = math.MaxInt64
+
+// Proof of work data structure definition
+//
+// prepareData: returns the data to hash for a given nonce, normally the
+// serialized block header with its nonce replaced
+// target: the target value the generated hash will be compared to
+type ProofOfWork struct {
+	prepareData func(nonce int) []byte
+	target      *big.Int
+}
+
+// Method to create a new Proof of Work
+// Initialize a big int with a value of 1 and shift it left by 256 - bits.
+// 256 is used as its the length of the SHA-256 hashing algorithm
+func NewProofOfWork(bits int, prepareData func(nonce int) []byte) *ProofOfWork {
+	target := big.NewInt(1)
+	target.Lsh(target, uint(256-bits))
+
+	pow := &ProofOfWork{prepareData, target}
+
+	return pow
+}
+
+// Implement the core of the ProofOfWork functionality
+// Initialized the data, hashes the data, validates the hash
+func (pow *ProofOfWork) Run() (int, []byte) {
+	var hashInt big.Int
+	var hash [32]byte
+	nonce := 0
+	start := time.Now()
+
+	log.Debug("mining a new block", "target", hex.EncodeToString(pow.target.Bytes()), "max_nonce", MaxNonce)
+	for nonce < MaxNonce {
+		data := pow.prepareData(nonce)
+		hash = sha256.Sum256(data)
+		hashInt.SetBytes(hash[:])
+
+		if hashInt.Cmp(pow.target) == -1 {
+			break
+		} else {
+			nonce++
+		}
+
+		if nonce%progressInterval == 0 {
+			log.Debug("mining progress", "nonce", nonce, "elapsed", time.Since(start))
+		}
+	}
+	log.Debug("found nonce", "hash", hex.EncodeToString(hash[:]), "nonce", nonce, "elapsed", time.Since(start))
+
+	return nonce, hash[:]
+}
+
+// Convert integer into hexidecimal value
+func IntToHex(n int64) []byte {
+	buff := make([]byte, 8)
+	binary.BigEndian.PutUint64(buff, uint64(n))
+
+	return buff
+}
+
+// functionality to validate the output of ProofOfWork, checking that the
+// data hashed with nonce meets the target
+func (pow *ProofOfWork) Validate(nonce int) bool {
+	var hashInt big.Int
+
+	data := pow.prepareData(nonce)
+	hash := sha256.Sum256(data)
+	hashInt.SetBytes(hash[:])
+
+	isValid := hashInt.Cmp(pow.target) == -1
+
+	return isValid
+}
@@ -0,0 +1,66 @@
+// Package base58 implements the Base58 encoding used for addresses
+package base58
+
+import (
+	"bytes"
+	"math/big"
+)
+
+// Alphabet lists the Base58 digits in order of value
+const Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var b58Alphabet = []byte(Alphabet)
+
+// Encode encodes a byte array to Base58
+func Encode(input []byte) []byte {
+	var result []byte
+
+	x := big.NewInt(0).SetBytes(input)
+
+	base := big.NewInt(int64(len(b58Alphabet)))
+	zero := big.NewInt(0)
+	mod := &big.Int{}
+
+	for x.Cmp(zero) != 0 {
+		x.DivMod(x, base, mod)
+		result = append(result, b58Alphabet[mod.Int64()])
+	}
+
+	// every leading zero byte is a leading 1, see
+	// https://en.bitcoin.it/wiki/Base58Check_encoding#Version_bytes
+	for _, b := range input {
+		if b != 0x00 {
+			break
+		}
+		result = append(result, b58Alphabet[0])
+	}
+
+	ReverseBytes(result)
+
+	return result
+}
+
+// Decode decodes Base58-encoded data
+func Decode(input []byte) []byte {
+	result := big.NewInt(0)
+
+	for _, b := range input {
+		charIndex := bytes.IndexByte(b58Alphabet, b)
+		result.Mul(result, big.NewInt(58))
+		result.Add(result, big.NewInt(int64(charIndex)))
+	}
+
+	var zeros int
+	for zeros < len(input) && input[zeros] == b58Alphabet[0] {
+		zeros++
+	}
+
+	return append(make([]byte, zeros), result.Bytes()...)
+}
+
+// ReverseBytes reverses data in place
+func ReverseBytes(data []byte) {
+	for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+		data[i], data[j] = data[j], data[i]
+	}
+}
@@ -0,0 +1,27 @@
+package base58
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeLeadingZeros(t *testing.T) {
+	tests := []struct {
+		input   []byte
+		encoded string
+	}{
+		{[]byte{0x00}, "1"},
+		{[]byte{0x00, 0x00, 0x01}, "112"},
+		{[]byte{0x00, 0x00, 0x00, 0xff}, "1115Q"},
+		{[]byte{0x39}, "z"},
+	}
+
+	for _, tt := range tests {
+		if got := string(Encode(tt.input)); got != tt.encoded {
+			t.Errorf("Encode(%x) = %s, want %s", tt.input, got, tt.encoded)
+		}
+		if got := Decode([]byte(tt.encoded)); !bytes.Equal(got, tt.input) {
+			t.Errorf("Decode(%s) = %x, want %x", tt.encoded, got, tt.input)
+		}
+	}
+}
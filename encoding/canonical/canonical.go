@@ -0,0 +1,194 @@
+// Package canonical implements the canonical binary serialization used for
+// hashing, storage and the wire.  See docs/serialization.md for the full
+// specification.
+//
+// Every value has exactly one valid encoding: varints must be minimally
+// encoded and decoders reject trailing data, so hashes computed over the
+// serialized form are stable across implementations.
+package canonical
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// MaxFieldLen is the maximum length accepted for a single length-prefixed
+// field
+const MaxFieldLen = 32 * 1024 * 1024
+
+var (
+	errNonCanonicalVarint = errors.New("varint is not minimally encoded")
+	errFieldTooLong       = errors.New("length-prefixed field exceeds maximum size")
+	errTrailingData       = errors.New("unexpected trailing data")
+	errIntOverflow        = errors.New("uvarint overflows an int")
+)
+
+// Encoder accumulates the canonical encoding of a value
+type Encoder struct {
+	buf bytes.Buffer
+}
+
+// WriteUvarint appends an unsigned varint
+func (e *Encoder) WriteUvarint(v uint64) {
+	var scratch [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(scratch[:], v)
+	e.buf.Write(scratch[:n])
+}
+
+// Signed integers are zigzag encoded so small negative values stay short
+func (e *Encoder) WriteVarint(v int64) {
+	var scratch [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(scratch[:], v)
+	e.buf.Write(scratch[:n])
+}
+
+// WriteBytes appends b prefixed with its length
+func (e *Encoder) WriteBytes(b []byte) {
+	e.WriteUvarint(uint64(len(b)))
+	e.buf.Write(b)
+}
+
+// Write appends raw bytes without a length prefix
+func (e *Encoder) Write(b []byte) {
+	e.buf.Write(b)
+}
+
+// Bytes returns the encoding accumulated so far
+func (e *Encoder) Bytes() []byte {
+	return e.buf.Bytes()
+}
+
+// Decoder reads a canonical encoding, remembering the first error so that
+// callers can check it once after reading every field
+type Decoder struct {
+	r   *bytes.Reader
+	err error
+}
+
+// NewDecoder returns a Decoder reading data
+func NewDecoder(data []byte) *Decoder {
+	return &Decoder{r: bytes.NewReader(data)}
+}
+
+// Err returns the first error encountered, if any
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+// ReadUvarint reads an unsigned varint
+func (d *Decoder) ReadUvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+
+	start := d.r.Len()
+	v, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		d.Fail(err)
+		return 0
+	}
+
+	var scratch [binary.MaxVarintLen64]byte
+	if start-d.r.Len() != binary.PutUvarint(scratch[:], v) {
+		d.Fail(errNonCanonicalVarint)
+		return 0
+	}
+
+	return v
+}
+
+// ReadVarint reads a zigzag encoded signed varint
+func (d *Decoder) ReadVarint() int64 {
+	if d.err != nil {
+		return 0
+	}
+
+	start := d.r.Len()
+	v, err := binary.ReadVarint(d.r)
+	if err != nil {
+		d.Fail(err)
+		return 0
+	}
+
+	var scratch [binary.MaxVarintLen64]byte
+	if start-d.r.Len() != binary.PutVarint(scratch[:], v) {
+		d.Fail(errNonCanonicalVarint)
+		return 0
+	}
+
+	return v
+}
+
+// ReadBytes reads a length-prefixed byte string
+func (d *Decoder) ReadBytes() []byte {
+	n := d.ReadUvarint()
+	if d.err != nil {
+		return nil
+	}
+	if n > MaxFieldLen || n > uint64(d.r.Len()) {
+		d.Fail(errFieldTooLong)
+		return nil
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		d.Fail(err)
+		return nil
+	}
+
+	return b
+}
+
+// ReadInt reads an unsigned varint that must fit in an int, so that it
+// cannot wrap to a negative value
+func (d *Decoder) ReadInt() int {
+	v := d.ReadUvarint()
+	if v > math.MaxInt {
+		d.Fail(errIntOverflow)
+		return 0
+	}
+
+	return int(v)
+}
+
+// ReadCount reads a count of following items, rejecting counts that could
+// not possibly fit in the remaining input
+func (d *Decoder) ReadCount() int {
+	n := d.ReadUvarint()
+	if d.err != nil {
+		return 0
+	}
+	if n > uint64(d.r.Len()) {
+		d.Fail(errFieldTooLong)
+		return 0
+	}
+
+	return int(n)
+}
+
+// Fail records err unless an earlier error was recorded
+func (d *Decoder) Fail(err error) {
+	if d.err == nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		d.err = err
+	}
+}
+
+// Finish reports the first decoding error, or an error if input remains
+// unread
+func (d *Decoder) Finish() error {
+	if d.err != nil {
+		return d.err
+	}
+	if d.r.Len() != 0 {
+		return fmt.Errorf("%w: %d bytes", errTrailingData, d.r.Len())
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package canonical
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	var e Encoder
+	e.WriteUvarint(0)
+	e.WriteUvarint(math.MaxUint64)
+	e.WriteVarint(-1)
+	e.WriteVarint(math.MinInt64)
+	e.WriteBytes(nil)
+	e.WriteBytes([]byte("canonical"))
+	e.WriteUvarint(math.MaxInt64)
+	e.WriteUvarint(2)
+	e.Write([]byte{0xaa, 0xbb})
+
+	d := NewDecoder(e.Bytes())
+	if v := d.ReadUvarint(); v != 0 {
+		t.Errorf("uvarint %d, want 0", v)
+	}
+	if v := d.ReadUvarint(); v != math.MaxUint64 {
+		t.Errorf("uvarint %d, want %d", v, uint64(math.MaxUint64))
+	}
+	if v := d.ReadVarint(); v != -1 {
+		t.Errorf("varint %d, want -1", v)
+	}
+	if v := d.ReadVarint(); v != math.MinInt64 {
+		t.Errorf("varint %d, want %d", v, math.MinInt64)
+	}
+	if b := d.ReadBytes(); len(b) != 0 {
+		t.Errorf("bytes %x, want none", b)
+	}
+	if b := d.ReadBytes(); string(b) != "canonical" {
+		t.Errorf("bytes %q, want %q", b, "canonical")
+	}
+	if v := d.ReadInt(); v != math.MaxInt64 {
+		t.Errorf("int %d, want %d", v, math.MaxInt64)
+	}
+	if n := d.ReadCount(); n != 2 {
+		t.Errorf("count %d, want 2", n)
+	}
+	raw := make([]byte, 2)
+	if _, err := io.ReadFull(d.r, raw); err != nil || !bytes.Equal(raw, []byte{0xaa, 0xbb}) {
+		t.Errorf("raw bytes %x, %v", raw, err)
+	}
+	if err := d.Finish(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Known encodings, so that the format cannot change unnoticed
+func TestEncoding(t *testing.T) {
+	tests := []struct {
+		name   string
+		encode func(e *Encoder)
+		want   []byte
+	}{
+		{"uvarint 0", func(e *Encoder) { e.WriteUvarint(0) }, []byte{0x00}},
+		{"uvarint 127", func(e *Encoder) { e.WriteUvarint(127) }, []byte{0x7f}},
+		{"uvarint 128", func(e *Encoder) { e.WriteUvarint(128) }, []byte{0x80, 0x01}},
+		{"varint -1", func(e *Encoder) { e.WriteVarint(-1) }, []byte{0x01}},
+		{"varint 1", func(e *Encoder) { e.WriteVarint(1) }, []byte{0x02}},
+		{"varint -65", func(e *Encoder) { e.WriteVarint(-65) }, []byte{0x81, 0x01}},
+		{"bytes", func(e *Encoder) { e.WriteBytes([]byte{0xca, 0xfe}) }, []byte{0x02, 0xca, 0xfe}},
+		{"empty bytes", func(e *Encoder) { e.WriteBytes(nil) }, []byte{0x00}},
+	}
+
+	for _, tt := range tests {
+		var e Encoder
+		tt.encode(&e)
+		if !bytes.Equal(e.Bytes(), tt.want) {
+			t.Errorf("%s: %x, want %x", tt.name, e.Bytes(), tt.want)
+		}
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		decode func(d *Decoder)
+		err    error
+	}{
+		{"uvarint with a zero continuation", []byte{0x80, 0x00}, func(d *Decoder) { d.ReadUvarint() }, errNonCanonicalVarint},
+		{"uvarint 1 in two bytes", []byte{0x81, 0x00}, func(d *Decoder) { d.ReadUvarint() }, errNonCanonicalVarint},
+		{"varint with a zero continuation", []byte{0x82, 0x80, 0x00}, func(d *Decoder) { d.ReadVarint() }, errNonCanonicalVarint},
+		{"truncated uvarint", []byte{0x80}, func(d *Decoder) { d.ReadUvarint() }, io.ErrUnexpectedEOF},
+		{"empty input", nil, func(d *Decoder) { d.ReadVarint() }, io.ErrUnexpectedEOF},
+		{"trailing data", []byte{0x01, 0x02}, func(d *Decoder) { d.ReadUvarint() }, errTrailingData},
+		{"bytes beyond the input", []byte{0x03, 0xaa, 0xbb}, func(d *Decoder) { d.ReadBytes() }, errFieldTooLong},
+		{"count beyond the input", []byte{0x02, 0xaa}, func(d *Decoder) { d.ReadCount() }, errFieldTooLong},
+		{"int overflow", []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x80, 0x01}, func(d *Decoder) { d.ReadInt() }, errIntOverflow},
+		{"first error kept", []byte{0x80, 0x00, 0x03}, func(d *Decoder) { d.ReadUvarint(); d.ReadBytes() }, errNonCanonicalVarint},
+	}
+
+	for _, tt := range tests {
+		d := NewDecoder(tt.data)
+		tt.decode(d)
+		if err := d.Finish(); !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+// A field longer than MaxFieldLen is refused before it is allocated
+func TestReadBytesMaxFieldLen(t *testing.T) {
+	var e Encoder
+	e.WriteUvarint(MaxFieldLen + 1)
+	e.Write(make([]byte, MaxFieldLen+1))
+
+	d := NewDecoder(e.Bytes())
+	if b := d.ReadBytes(); b != nil || !errors.Is(d.Err(), errFieldTooLong) {
+		t.Fatalf("read %d bytes, %v", len(b), d.Err())
+	}
+}
@@ -0,0 +1,215 @@
+// Package ecc encodes P-256 public keys and ECDSA signatures and parses them
+// strictly, so that every key and signature has exactly one valid encoding
+//
+// Public keys are SEC1 compressed points: a 0x02 or 0x03 prefix giving the
+// parity of Y followed by the 32 byte big-endian X coordinate.  Wallets
+// created before compressed keys hold legacy keys, the X and Y coordinates
+// concatenated without their leading zero bytes, which are still accepted
+// so that their outputs can be spent.
+//
+// Signatures are DER encoded SEQUENCEs of the INTEGERs r and s, with s in
+// the lower half of the curve order.  As (r, N-s) is as valid as (r, s),
+// accepting both would let anyone change a transaction's signature, and so
+// the hash of the block holding it, without invalidating it.
+package ecc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"filippo.io/nistec"
+)
+
+// CompressedPubKeyLen is the length of a SEC1 compressed public key
+const CompressedPubKeyLen = 33
+
+// Length of a coordinate or scalar of the curve
+const scalarLen = 32
+
+// Errors returned by the parsers.  Callers can test for them with errors.Is.
+var (
+	ErrInvalidPubKey    = errors.New("invalid public key encoding")
+	ErrInvalidSignature = errors.New("invalid signature encoding")
+)
+
+var curve = elliptic.P256()
+
+// halfOrder is the largest s of a low-S signature
+var halfOrder = new(big.Int).Rsh(curve.Params().N, 1)
+
+// CompressPubKey returns the SEC1 compressed encoding of a public key
+func CompressPubKey(pub *ecdsa.PublicKey) []byte {
+	return elliptic.MarshalCompressed(curve, pub.X, pub.Y)
+}
+
+// LegacyPubKey returns the legacy encoding of a public key, the X and Y
+// coordinates concatenated without their leading zero bytes
+func LegacyPubKey(pub *ecdsa.PublicKey) []byte {
+	return append(pub.X.Bytes(), pub.Y.Bytes()...)
+}
+
+// ParsePubKey decodes a compressed or legacy public key, which must be a
+// point of the curve
+func ParsePubKey(pubKey []byte) (*ecdsa.PublicKey, error) {
+	if len(pubKey) == CompressedPubKeyLen {
+		x, y := elliptic.UnmarshalCompressed(curve, pubKey)
+		if x == nil {
+			return nil, fmt.Errorf("%w: not a compressed point of the curve", ErrInvalidPubKey)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return parseLegacyPubKey(pubKey)
+}
+
+// A legacy key does not record where X ends when a coordinate is shorter
+// than 32 bytes, so every split is tried and exactly one must give a point
+// of the curve.  Splits leaving a coordinate with a leading zero byte are
+// not encodings LegacyPubKey produces.
+func parseLegacyPubKey(pubKey []byte) (*ecdsa.PublicKey, error) {
+	if len(pubKey) <= CompressedPubKeyLen || len(pubKey) > 2*scalarLen {
+		return nil, fmt.Errorf("%w: %d bytes", ErrInvalidPubKey, len(pubKey))
+	}
+
+	var found *ecdsa.PublicKey
+	for xLen := len(pubKey) - scalarLen; xLen <= scalarLen; xLen++ {
+		xBytes, yBytes := pubKey[:xLen], pubKey[xLen:]
+		if xBytes[0] == 0 || yBytes[0] == 0 {
+			continue
+		}
+
+		x := new(big.Int).SetBytes(xBytes)
+		y := new(big.Int).SetBytes(yBytes)
+		point := append(append([]byte{0x04}, x.FillBytes(make([]byte, scalarLen))...), y.FillBytes(make([]byte, scalarLen))...)
+		if _, err := nistec.NewP256Point().SetBytes(point); err != nil {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("%w: ambiguous legacy key", ErrInvalidPubKey)
+		}
+		found = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: not a point of the curve", ErrInvalidPubKey)
+	}
+
+	return found, nil
+}
+
+// Sign signs hash with privKey, returning the DER encoded low-S signature
+func Sign(privKey *ecdsa.PrivateKey, hash []byte) ([]byte, error) {
+	r, s, err := SignRS(privKey, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return EncodeSignature(r, s), nil
+}
+
+// Verify reports whether signature is a valid signature of hash by pubKey,
+// both strictly encoded
+func Verify(pubKey, hash, signature []byte) error {
+	pub, err := ParsePubKey(pubKey)
+	if err != nil {
+		return err
+	}
+	r, s, err := ParseSignature(signature)
+	if err != nil {
+		return err
+	}
+	if !ecdsa.Verify(pub, hash, r, s) {
+		return errors.New("signature does not match")
+	}
+
+	return nil
+}
+
+// EncodeSignature returns the DER encoding of (r, s), replacing s with N-s
+// if it is in the upper half of the curve order
+func EncodeSignature(r, s *big.Int) []byte {
+	if s.Cmp(halfOrder) > 0 {
+		s = new(big.Int).Sub(curve.Params().N, s)
+	}
+
+	rBytes := derInteger(r)
+	sBytes := derInteger(s)
+
+	der := []byte{0x30, byte(len(rBytes) + len(sBytes))}
+	der = append(der, rBytes...)
+
+	return append(der, sBytes...)
+}
+
+// Encode a positive integer below 2^256 as a DER INTEGER, with a leading
+// zero byte when its top bit is set so that it is not read as negative
+func derInteger(v *big.Int) []byte {
+	b := v.Bytes()
+	if len(b) == 0 || b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+
+	return append([]byte{0x02, byte(len(b))}, b...)
+}
+
+// ParseSignature decodes a DER signature, rejecting any encoding other than
+// the one EncodeSignature produces: lengths must be exact, integers
+// minimally encoded and positive, r and s below the curve order and s in
+// its lower half
+func ParseSignature(signature []byte) (*big.Int, *big.Int, error) {
+	// every length fits in one byte, as r and s take at most 33 bytes each
+	if len(signature) < 8 || len(signature) > 2+2*(2+scalarLen+1) {
+		return nil, nil, fmt.Errorf("%w: %d bytes", ErrInvalidSignature, len(signature))
+	}
+	if signature[0] != 0x30 || int(signature[1]) != len(signature)-2 {
+		return nil, nil, fmt.Errorf("%w: bad sequence header", ErrInvalidSignature)
+	}
+
+	r, rest, err := parseDERInteger(signature[2:])
+	if err != nil {
+		return nil, nil, err
+	}
+	s, rest, err := parseDERInteger(rest)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rest) != 0 {
+		return nil, nil, fmt.Errorf("%w: trailing bytes", ErrInvalidSignature)
+	}
+
+	if r.Cmp(curve.Params().N) >= 0 || s.Cmp(curve.Params().N) >= 0 {
+		return nil, nil, fmt.Errorf("%w: integer not below the curve order", ErrInvalidSignature)
+	}
+	if s.Cmp(halfOrder) > 0 {
+		return nil, nil, fmt.Errorf("%w: s is not in the lower half of the curve order", ErrInvalidSignature)
+	}
+
+	return r, s, nil
+}
+
+func parseDERInteger(der []byte) (*big.Int, []byte, error) {
+	if len(der) < 3 || der[0] != 0x02 {
+		return nil, nil, fmt.Errorf("%w: expected an integer", ErrInvalidSignature)
+	}
+	length := int(der[1])
+	if length == 0 || length > scalarLen+1 || length > len(der)-2 {
+		return nil, nil, fmt.Errorf("%w: bad integer length", ErrInvalidSignature)
+	}
+
+	b := der[2 : 2+length]
+	if b[0]&0x80 != 0 {
+		return nil, nil, fmt.Errorf("%w: negative integer", ErrInvalidSignature)
+	}
+	if length > 1 && b[0] == 0 && b[1]&0x80 == 0 {
+		return nil, nil, fmt.Errorf("%w: integer not minimally encoded", ErrInvalidSignature)
+	}
+
+	v := new(big.Int).SetBytes(b)
+	if v.Sign() == 0 {
+		return nil, nil, fmt.Errorf("%w: zero integer", ErrInvalidSignature)
+	}
+
+	return v, der[2+length:], nil
+}
@@ -0,0 +1,242 @@
+package ecc
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sort"
+
+	"filippo.io/bigmod"
+	"filippo.io/nistec"
+)
+
+// Keys are aggregated and signed for as in MuSig2: the aggregate of the keys
+// P_i is sum(a_i*P_i), each a_i a hash of every key and of P_i so that no
+// signer can choose a key cancelling the others, and it is an ordinary
+// Schnorr key.  Signing takes two rounds: the signers exchange their public
+// nonces, then their partial signatures, which add up to an ordinary
+// Schnorr signature, so an output locked to aggregated keys looks like any
+// other on chain.  The keys are sorted first, so their order does not
+// matter.
+
+const (
+	keyListTag   = "TCN/keyagg list"
+	keyCoefTag   = "TCN/keyagg coef"
+	nonceGenTag  = "TCN/musig nonce"
+	nonceCoefTag = "TCN/musig noncecoef"
+)
+
+// MuSigNonceLen is the length of a public nonce, two compressed points
+const MuSigNonceLen = 2 * CompressedPubKeyLen
+
+// keyAggregate holds the aggregate of a set of keys
+//
+// keys: the sorted keys
+// coef: the coefficient a_i of each key
+// odd: 1 if the aggregate point has an odd Y, 0 if it is even
+// key: the aggregate Schnorr public key, the X coordinate of the point
+type keyAggregate struct {
+	keys [][]byte
+	coef map[string]*bigmod.Nat
+	odd  int
+	key  []byte
+}
+
+func aggregate(pubKeys [][]byte) (*keyAggregate, error) {
+	if len(pubKeys) == 0 {
+		return nil, errors.New("no keys to aggregate")
+	}
+
+	keys := make([][]byte, len(pubKeys))
+	copy(keys, pubKeys)
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i], keys[j]) < 0
+	})
+
+	list := taggedHash(keyListTag, keys...)
+	agg := &keyAggregate{keys: keys, coef: make(map[string]*bigmod.Nat)}
+	sum := nistec.NewP256Point()
+	for _, key := range keys {
+		p, err := liftX(key)
+		if err != nil {
+			return nil, err
+		}
+
+		a := hashScalar(taggedHash(keyCoefTag, list, key))
+		agg.coef[string(key)] = a
+		sum.Add(sum, scalarMult(p, a))
+	}
+
+	var err error
+	agg.key, agg.odd, err = pointXY(sum)
+	if err != nil {
+		return nil, errors.New("keys aggregate to the point at infinity")
+	}
+
+	return agg, nil
+}
+
+// AggregateKeys returns the Schnorr public key aggregating pubKeys
+func AggregateKeys(pubKeys [][]byte) ([]byte, error) {
+	agg, err := aggregate(pubKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return agg.key, nil
+}
+
+// MuSigNonce is a signer's secret nonce for one signing session.  It must
+// never be used for two signatures, as that reveals the private key, and
+// MuSigPartialSign clears it.
+type MuSigNonce struct {
+	k1, k2 *bigmod.Nat
+
+	// Public is the nonce to send to the other signers
+	Public []byte
+}
+
+// NewMuSigNonce draws a nonce to sign hash with privKey.  The nonce is
+// random, mixed with the key and the hash.
+func NewMuSigNonce(privKey *ecdsa.PrivateKey, hash []byte) (*MuSigNonce, error) {
+	d, err := privateScalar(privKey)
+	if err != nil {
+		return nil, err
+	}
+	seed := make([]byte, scalarLen)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+
+	nonce := &MuSigNonce{}
+	for i, k := range []**bigmod.Nat{&nonce.k1, &nonce.k2} {
+		*k = hashScalar(taggedHash(nonceGenTag, seed, scalarBytes(d), hash, []byte{byte(i)}))
+		if (*k).IsZero() == 1 {
+			return nil, errors.New("derived a zero nonce")
+		}
+		nonce.Public = append(nonce.Public, scalarBaseMult(*k).BytesCompressed()...)
+	}
+
+	return nonce, nil
+}
+
+// Sum the public nonces, returning the X coordinate of the nonce point R,
+// 1 if its Y is odd or 0 if it is even, and its coefficient b
+func aggregateNonces(agg *keyAggregate, publicNonces [][]byte, hash []byte) ([]byte, int, *bigmod.Nat, error) {
+	r1, r2 := nistec.NewP256Point(), nistec.NewP256Point()
+	for _, public := range publicNonces {
+		if len(public) != MuSigNonceLen {
+			return nil, 0, nil, fmt.Errorf("public nonce of %d bytes", len(public))
+		}
+		p1, err1 := nistec.NewP256Point().SetBytes(public[:CompressedPubKeyLen])
+		p2, err2 := nistec.NewP256Point().SetBytes(public[CompressedPubKeyLen:])
+		if err1 != nil || err2 != nil {
+			return nil, 0, nil, errors.New("public nonce is not a pair of points of the curve")
+		}
+		r1.Add(r1, p1)
+		r2.Add(r2, p2)
+	}
+	if _, _, err := pointXY(r1); err != nil {
+		return nil, 0, nil, errors.New("nonces aggregate to the point at infinity")
+	}
+	if _, _, err := pointXY(r2); err != nil {
+		return nil, 0, nil, errors.New("nonces aggregate to the point at infinity")
+	}
+
+	b := hashScalar(taggedHash(nonceCoefTag, r1.BytesCompressed(), r2.BytesCompressed(), agg.key, hash))
+
+	// R = R1 + b*R2
+	r := r1.Add(r1, scalarMult(r2, b))
+	rx, odd, err := pointXY(r)
+	if err != nil {
+		return nil, 0, nil, errors.New("nonces aggregate to the point at infinity")
+	}
+
+	return rx, odd, b, nil
+}
+
+// MuSigPartialSign returns privKey's partial signature of hash for the key
+// aggregating pubKeys, given the public nonces of every signer, its own
+// included
+func MuSigPartialSign(privKey *ecdsa.PrivateKey, nonce *MuSigNonce, pubKeys, publicNonces [][]byte, hash []byte) ([]byte, error) {
+	if nonce.k1 == nil {
+		return nil, errors.New("nonce already used")
+	}
+	if len(publicNonces) != len(pubKeys) {
+		return nil, fmt.Errorf("%d public nonces for %d keys", len(publicNonces), len(pubKeys))
+	}
+	found := false
+	for _, public := range publicNonces {
+		found = found || bytes.Equal(public, nonce.Public)
+	}
+	if !found {
+		return nil, errors.New("own public nonce is missing")
+	}
+
+	agg, err := aggregate(pubKeys)
+	if err != nil {
+		return nil, err
+	}
+	d, err := privateScalar(privKey)
+	if err != nil {
+		return nil, err
+	}
+	d, pubKey := evenKey(d)
+	a, ok := agg.coef[string(pubKey)]
+	if !ok {
+		return nil, errors.New("private key is not one of the aggregated keys")
+	}
+
+	rx, odd, b, err := aggregateNonces(agg, publicNonces, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	// k = k1 + b*k2, negated if R has an odd Y
+	k := negateIf(cloneScalar(b).Mul(nonce.k2, order).Add(nonce.k1, order), odd)
+	nonce.k1, nonce.k2 = nil, nil
+
+	// the aggregate key is the point with the even Y, so the keys are
+	// negated if the sum has an odd Y
+	d = negateIf(d, agg.odd)
+
+	// s_i = k + e*a_i*d_i mod n
+	s := challenge(rx, agg.key, hash).Mul(a, order).Mul(d, order).Add(k, order)
+
+	return scalarBytes(s), nil
+}
+
+// MuSigCombine adds up the partial signatures of hash into a Schnorr
+// signature by the key aggregating pubKeys, and checks it
+func MuSigCombine(pubKeys, publicNonces, partials [][]byte, hash []byte) ([]byte, error) {
+	if len(partials) != len(pubKeys) {
+		return nil, fmt.Errorf("%d partial signatures for %d keys", len(partials), len(pubKeys))
+	}
+
+	agg, err := aggregate(pubKeys)
+	if err != nil {
+		return nil, err
+	}
+	rx, _, _, err := aggregateNonces(agg, publicNonces, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	s := bigmod.NewNat().ExpandFor(order)
+	for _, partial := range partials {
+		si, err := parseScalar(partial)
+		if err != nil {
+			return nil, errors.New("invalid partial signature")
+		}
+		s.Add(si, order)
+	}
+
+	signature := append(rx, scalarBytes(s)...)
+	if err := SchnorrVerify(agg.key, hash, signature); err != nil {
+		return nil, err
+	}
+
+	return signature, nil
+}
@@ -0,0 +1,159 @@
+package ecc
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func musigKeys(t *testing.T) ([]*ecdsa.PrivateKey, [][]byte) {
+	t.Helper()
+
+	var privKeys []*ecdsa.PrivateKey
+	var pubKeys [][]byte
+	for _, v := range schnorrVectors[:3] {
+		privKey := testKey(t, v.key)
+		privKeys = append(privKeys, privKey)
+		pubKeys = append(pubKeys, SchnorrPubKey(privKey))
+	}
+
+	return privKeys, pubKeys
+}
+
+// Run both rounds of MuSig2 for hash, returning the public nonces and the
+// partial signatures of every signer
+func musigSign(t *testing.T, privKeys []*ecdsa.PrivateKey, pubKeys [][]byte, hash []byte) ([][]byte, [][]byte) {
+	t.Helper()
+
+	nonces := make([]*MuSigNonce, len(privKeys))
+	var publicNonces [][]byte
+	for i, privKey := range privKeys {
+		nonce, err := NewMuSigNonce(privKey, hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonces[i] = nonce
+		publicNonces = append(publicNonces, nonce.Public)
+	}
+
+	var partials [][]byte
+	for i, privKey := range privKeys {
+		partial, err := MuSigPartialSign(privKey, nonces[i], pubKeys, publicNonces, hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		partials = append(partials, partial)
+	}
+
+	return publicNonces, partials
+}
+
+func TestAggregateKeys(t *testing.T) {
+	_, pubKeys := musigKeys(t)
+
+	key, err := AggregateKeys(pubKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "2a11ad3c37749ae0fc147d3bd403253c8e15daf72f35f5dc4a89b2243b4866b7"
+	if got := hex.EncodeToString(key); got != want {
+		t.Fatalf("aggregate key %s, want %s", got, want)
+	}
+
+	reversed := [][]byte{pubKeys[2], pubKeys[1], pubKeys[0]}
+	if other, err := AggregateKeys(reversed); err != nil || hex.EncodeToString(other) != want {
+		t.Fatalf("aggregating the keys in another order gave %x, %v", other, err)
+	}
+	if _, err := ParseSchnorrPubKey(key); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := AggregateKeys(nil); err == nil {
+		t.Fatal("aggregated no keys")
+	}
+	if _, err := AggregateKeys([][]byte{pubKeys[0], offCurveX(t)}); err == nil {
+		t.Fatal("aggregated a key off the curve")
+	}
+}
+
+func TestMuSig(t *testing.T) {
+	privKeys, pubKeys := musigKeys(t)
+	hash := sha256.Sum256([]byte("spend the aggregated output"))
+
+	key, err := AggregateKeys(pubKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicNonces, partials := musigSign(t, privKeys, pubKeys, hash[:])
+
+	signature, err := MuSigCombine(pubKeys, publicNonces, partials, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SchnorrVerify(key, hash[:], signature); err != nil {
+		t.Fatalf("the combined signature does not verify: %v", err)
+	}
+	other := sha256.Sum256([]byte("another spend"))
+	if err := SchnorrVerify(key, other[:], signature); err == nil {
+		t.Fatal("the combined signature verifies another hash")
+	}
+}
+
+func TestMuSigRejects(t *testing.T) {
+	privKeys, pubKeys := musigKeys(t)
+	hash := sha256.Sum256([]byte("spend the aggregated output"))
+
+	t.Run("reused nonce", func(t *testing.T) {
+		nonce, err := NewMuSigNonce(privKeys[0], hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		publicNonces := [][]byte{nonce.Public, nonce.Public, nonce.Public}
+		if _, err := MuSigPartialSign(privKeys[0], nonce, pubKeys, publicNonces, hash[:]); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := MuSigPartialSign(privKeys[0], nonce, pubKeys, publicNonces, hash[:]); err == nil {
+			t.Fatal("signed twice with the same nonce")
+		}
+	})
+
+	t.Run("foreign key", func(t *testing.T) {
+		outsider := testKey(t, schnorrVectors[3].key)
+		nonce, err := NewMuSigNonce(outsider, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		publicNonces := [][]byte{nonce.Public, nonce.Public, nonce.Public}
+		if _, err := MuSigPartialSign(outsider, nonce, pubKeys, publicNonces, hash[:]); err == nil {
+			t.Fatal("signed for keys that do not include the signer's")
+		}
+	})
+
+	t.Run("missing own nonce", func(t *testing.T) {
+		nonce, err := NewMuSigNonce(privKeys[0], hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		publicNonces, _ := musigSign(t, privKeys, pubKeys, hash[:])
+		if _, err := MuSigPartialSign(privKeys[0], nonce, pubKeys, publicNonces, hash[:]); err == nil {
+			t.Fatal("signed without the signer's own public nonce")
+		}
+	})
+
+	t.Run("altered partial signature", func(t *testing.T) {
+		publicNonces, partials := musigSign(t, privKeys, pubKeys, hash[:])
+		partials[1] = append([]byte(nil), partials[1]...)
+		partials[1][scalarLen-1] ^= 0x01
+		if _, err := MuSigCombine(pubKeys, publicNonces, partials, hash[:]); err == nil {
+			t.Fatal("combined an altered partial signature")
+		}
+	})
+
+	t.Run("missing partial signature", func(t *testing.T) {
+		publicNonces, partials := musigSign(t, privKeys, pubKeys, hash[:])
+		if _, err := MuSigCombine(pubKeys, publicNonces, partials[:2], hash[:]); err == nil {
+			t.Fatal("combined the partial signatures of two of three signers")
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package ecc
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"math/big"
+
+	"filippo.io/bigmod"
+)
+
+// Signatures use the deterministic nonces of RFC 6979 with HMAC-SHA256
+// instead of random ones, so signing the same hash with the same key always
+// gives the same signature and a weak random source cannot leak the key.
+
+// SignRS signs hash with privKey using an RFC 6979 nonce, returning r and s
+// as computed, before low-S normalization
+func SignRS(privKey *ecdsa.PrivateKey, hash []byte) (*big.Int, *big.Int, error) {
+	d, err := privateScalar(privKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	e := hashScalar(hashToInt(hash).FillBytes(make([]byte, scalarLen)))
+	nonces := newNonceGenerator(privKey.D, hash)
+
+	for {
+		k := nonces.next()
+
+		x, _, err := pointXY(scalarBaseMult(k))
+		if err != nil {
+			continue
+		}
+		r := hashScalar(x)
+		if r.IsZero() == 1 {
+			continue
+		}
+
+		// s = k^-1 (e + r d) mod n
+		s := bigmod.NewNat().ExpandFor(order).Add(r, order).Mul(d, order)
+		s.Add(e, order).Mul(invertScalar(k), order)
+		if s.IsZero() == 1 {
+			continue
+		}
+
+		return new(big.Int).SetBytes(scalarBytes(r)), new(big.Int).SetBytes(scalarBytes(s)), nil
+	}
+}
+
+// Convert a hash to an integer as ECDSA does, keeping its leftmost bits
+// when it is longer than the curve order
+func hashToInt(hash []byte) *big.Int {
+	orderBits := curve.Params().N.BitLen()
+	if len(hash) > (orderBits+7)/8 {
+		hash = hash[:(orderBits+7)/8]
+	}
+
+	v := new(big.Int).SetBytes(hash)
+	if excess := len(hash)*8 - orderBits; excess > 0 {
+		v.Rsh(v, uint(excess))
+	}
+
+	return v
+}
+
+// HMAC-DRBG state of RFC 6979 section 3.2
+type nonceGenerator struct {
+	k, v  []byte
+	first bool
+}
+
+func newNonceGenerator(d *big.Int, hash []byte) *nonceGenerator {
+	n := curve.Params().N
+	x := d.FillBytes(make([]byte, scalarLen))
+	h := new(big.Int).Mod(hashToInt(hash), n).FillBytes(make([]byte, scalarLen))
+
+	g := &nonceGenerator{k: make([]byte, sha256.Size), v: make([]byte, sha256.Size), first: true}
+	for i := range g.v {
+		g.v[i] = 0x01
+	}
+
+	g.k = g.mac(g.v, []byte{0x00}, x, h)
+	g.v = g.mac(g.v)
+	g.k = g.mac(g.v, []byte{0x01}, x, h)
+	g.v = g.mac(g.v)
+
+	return g
+}
+
+func (g *nonceGenerator) mac(data ...[]byte) []byte {
+	m := hmac.New(sha256.New, g.k)
+	for _, d := range data {
+		m.Write(d)
+	}
+
+	return m.Sum(nil)
+}
+
+// Return the next candidate nonce in [1, N-1]
+func (g *nonceGenerator) next() *bigmod.Nat {
+	for {
+		if !g.first {
+			g.k = g.mac(g.v, []byte{0x00})
+			g.v = g.mac(g.v)
+		}
+		g.first = false
+
+		// the curve order is 256 bits, so one HMAC output is a candidate
+		g.v = g.mac(g.v)
+		k, err := parseScalar(g.v)
+		if err == nil && k.IsZero() == 0 {
+			return k
+		}
+	}
+}
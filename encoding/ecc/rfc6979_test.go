@@ -0,0 +1,105 @@
+package ecc
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+// Key of RFC 6979 appendix A.2.5, P-256
+const rfc6979Key = "C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721"
+
+func hexInt(t *testing.T, s string) *big.Int {
+	t.Helper()
+
+	v, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		t.Fatalf("invalid hex integer %q", s)
+	}
+
+	return v
+}
+
+func rfc6979PrivateKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	d := hexInt(t, rfc6979Key)
+	x, y := curve.ScalarBaseMult(d.FillBytes(make([]byte, scalarLen)))
+
+	return &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: curve, X: x, Y: y}, D: d}
+}
+
+// The P-256 with SHA-256 vectors of RFC 6979 appendix A.2.5
+func TestRFC6979Vectors(t *testing.T) {
+	vectors := []struct {
+		message string
+		k, r, s string
+	}{
+		{
+			"sample",
+			"A6E3C57DD01ABE90086538398355DD4C3B17AA873382B0F24D6129493D8AAD60",
+			"EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716",
+			"F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8",
+		},
+		{
+			"test",
+			"D16B6AE827F17175E040871A1C7EC3500192C4C92677336EC2537ACAEE0008E0",
+			"F1ABB023518351CD71D881567B1EA663ED3EFCF6C5132B354F28D3B0B7D38367",
+			"019F4113742A2B14BD25926B49C649155F267E60D3814B4C0CC84250E46F0083",
+		},
+	}
+
+	privKey := rfc6979PrivateKey(t)
+	pubKey := CompressPubKey(&privKey.PublicKey)
+
+	for _, v := range vectors {
+		t.Run(v.message, func(t *testing.T) {
+			hash := sha256.Sum256([]byte(v.message))
+
+			k := new(big.Int).SetBytes(scalarBytes(newNonceGenerator(privKey.D, hash[:]).next()))
+			if want := hexInt(t, v.k); k.Cmp(want) != 0 {
+				t.Fatalf("k = %X, want %X", k, want)
+			}
+
+			r, s, err := SignRS(privKey, hash[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := hexInt(t, v.r); r.Cmp(want) != 0 {
+				t.Fatalf("r = %X, want %X", r, want)
+			}
+			if want := hexInt(t, v.s); s.Cmp(want) != 0 {
+				t.Fatalf("s = %X, want %X", s, want)
+			}
+
+			signature, err := Sign(privKey, hash[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := EncodeSignature(r, s); !bytes.Equal(signature, want) {
+				t.Fatalf("signature %x, want %x", signature, want)
+			}
+			if err := Verify(pubKey, hash[:], signature); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// The "sample" vector has a high s, which Sign replaces by N-s
+func TestSignLowS(t *testing.T) {
+	hash := sha256.Sum256([]byte("sample"))
+
+	signature, err := Sign(rfc6979PrivateKey(t), hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "3045022100efd48b2aacb6a8fd1140dd9cd45e81d69d2c877b56aaf991c34d0ea84eaf371602200834e36ad29a83bf2bc9385e491d6099c8fdf9d1ed67aa7ea5f51f93782857a9"
+	if got := hex.EncodeToString(signature); got != want {
+		t.Fatalf("signature %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,179 @@
+package ecc
+
+import (
+	"crypto/ecdsa"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+
+	"filippo.io/bigmod"
+	"filippo.io/nistec"
+)
+
+// Arithmetic on private keys and nonces goes through filippo.io/bigmod for
+// scalars modulo the curve order and filippo.io/nistec for points, whose
+// operations take the same time whatever the values, as math/big and the
+// crypto/elliptic point methods do not.  Branches only depend on public
+// values or on failures that abort signing.
+
+var order = bigmod.NewModulusFromBig(curve.Params().N)
+
+// Exponent of the inverse modulo the prime curve order, N-2
+var inverseExponent = new(big.Int).Sub(curve.Params().N, big.NewInt(2)).Bytes()
+
+// Decode a 32 byte scalar, which must be below the curve order
+func parseScalar(b []byte) (*bigmod.Nat, error) {
+	if len(b) != scalarLen {
+		return nil, fmt.Errorf("scalar of %d bytes", len(b))
+	}
+
+	return bigmod.NewNat().SetBytes(b, order)
+}
+
+// Interpret a 32 byte hash as a scalar modulo the curve order
+func hashScalar(hash []byte) *bigmod.Nat {
+	k, err := bigmod.NewNat().SetOverflowingBytes(hash, order)
+	if err != nil {
+		panic(err)
+	}
+
+	return k
+}
+
+// Return the scalar of privKey, which must lie in [1, N-1]
+func privateScalar(privKey *ecdsa.PrivateKey) (*bigmod.Nat, error) {
+	if privKey.D == nil || privKey.D.Sign() <= 0 || privKey.D.BitLen() > 8*scalarLen {
+		return nil, errors.New("invalid private key")
+	}
+	d, err := parseScalar(privKey.D.FillBytes(make([]byte, scalarLen)))
+	if err != nil || d.IsZero() == 1 {
+		return nil, errors.New("invalid private key")
+	}
+
+	return d, nil
+}
+
+// NewPrivateKey returns the key with the big-endian scalar d, which must lie
+// in [1, N-1], computing its public key in constant time
+func NewPrivateKey(d []byte) (*ecdsa.PrivateKey, error) {
+	if len(d) > scalarLen {
+		return nil, errors.New("invalid private key")
+	}
+	k, err := parseScalar(append(make([]byte, scalarLen-len(d)), d...))
+	if err != nil || k.IsZero() == 1 {
+		return nil, errors.New("invalid private key")
+	}
+	xy := scalarBaseMult(k).Bytes()[1:]
+
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(xy[:scalarLen]), Y: new(big.Int).SetBytes(xy[scalarLen:])},
+		D:         new(big.Int).SetBytes(d),
+	}, nil
+}
+
+func scalarBytes(k *bigmod.Nat) []byte {
+	return k.Bytes(order)
+}
+
+// Return a copy of k
+func cloneScalar(k *bigmod.Nat) *bigmod.Nat {
+	return bigmod.NewNat().ExpandFor(order).Add(k, order)
+}
+
+// Return -k if negate is 1 or k if it is 0, in constant time
+func negateIf(k *bigmod.Nat, negate int) *bigmod.Nat {
+	b := scalarBytes(k)
+	neg := scalarBytes(bigmod.NewNat().ExpandFor(order).Sub(k, order))
+	subtle.ConstantTimeCopy(negate, b, neg)
+
+	return hashScalar(b)
+}
+
+// Return k^-1 modulo the curve order, for a non-zero k
+func invertScalar(k *bigmod.Nat) *bigmod.Nat {
+	return bigmod.NewNat().Exp(k, inverseExponent, order)
+}
+
+// Return k*G
+func scalarBaseMult(k *bigmod.Nat) *nistec.P256Point {
+	p, err := nistec.NewP256Point().ScalarBaseMult(scalarBytes(k))
+	if err != nil {
+		panic(err)
+	}
+
+	return p
+}
+
+// Return k*p
+func scalarMult(p *nistec.P256Point, k *bigmod.Nat) *nistec.P256Point {
+	q, err := nistec.NewP256Point().ScalarMult(p, scalarBytes(k))
+	if err != nil {
+		panic(err)
+	}
+
+	return q
+}
+
+// Return the sum of the scalars[i]*points[i], for 32 byte big-endian
+// scalars, with Pippenger's bucket method: the scalars are cut into windows
+// of c bits, and for each window, from the top, the points are added into
+// the bucket of their digit and the buckets summed weighted by their digit.
+// This takes far fewer point operations than one ScalarMult per point.  It
+// is not constant time, so the scalars must be public.
+func multiScalarMult(points []*nistec.P256Point, scalars [][]byte) *nistec.P256Point {
+	c := bits.Len(uint(len(points))) - 3
+	if c < 2 {
+		c = 2
+	}
+
+	acc := nistec.NewP256Point()
+	buckets := make([]*nistec.P256Point, 1<<c)
+	for window := (8*scalarLen + c - 1) / c; window > 0; window-- {
+		for i := 0; i < c; i++ {
+			acc.Double(acc)
+		}
+		for d := range buckets {
+			buckets[d] = nistec.NewP256Point()
+		}
+		for i, p := range points {
+			if d := scalarDigit(scalars[i], (window-1)*c, c); d != 0 {
+				buckets[d].Add(buckets[d], p)
+			}
+		}
+
+		// sum(d*buckets[d]) as the sum of the running sums from the top
+		running, sum := nistec.NewP256Point(), nistec.NewP256Point()
+		for d := len(buckets) - 1; d > 0; d-- {
+			running.Add(running, buckets[d])
+			sum.Add(sum, running)
+		}
+		acc.Add(acc, sum)
+	}
+
+	return acc
+}
+
+// Return the c bits of the big-endian scalar starting at bit pos, counted
+// from the least significant one
+func scalarDigit(scalar []byte, pos, c int) int {
+	d := 0
+	for i := 0; i < c && pos+i < 8*len(scalar); i++ {
+		bit := pos + i
+		d |= int(scalar[len(scalar)-1-bit/8]>>(bit%8)&1) << i
+	}
+
+	return d
+}
+
+// Return the X coordinate of p and 1 if its Y is odd, 0 if it is even, or
+// an error for the point at infinity
+func pointXY(p *nistec.P256Point) ([]byte, int, error) {
+	compressed := p.BytesCompressed()
+	if len(compressed) != CompressedPubKeyLen {
+		return nil, 0, errors.New("point at infinity")
+	}
+
+	return compressed[1:], int(compressed[0] & 1), nil
+}
@@ -0,0 +1,44 @@
+package ecc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"filippo.io/nistec"
+)
+
+// The bucket method must give the sum of the products computed one by one,
+// whatever the number of points and so the window size
+func TestMultiScalarMult(t *testing.T) {
+	maxScalar := intBytes(new(big.Int).Sub(curve.Params().N, big.NewInt(1)))
+
+	for _, n := range []int{0, 1, 2, 5, 70} {
+		var points []*nistec.P256Point
+		var scalars [][]byte
+		want := nistec.NewP256Point()
+		for i := 0; i < n; i++ {
+			hash := sha256.Sum256([]byte(fmt.Sprint(i)))
+			point := scalarBaseMult(hashScalar(hash[:]))
+			scalar := scalarBytes(hashScalar(hash[:]))
+			switch i {
+			case 1:
+				scalar = make([]byte, scalarLen)
+			case 2:
+				scalar = maxScalar
+			case 3:
+				point = points[0]
+			}
+
+			points = append(points, point)
+			scalars = append(scalars, scalar)
+			want.Add(want, scalarMult(point, hashScalar(scalar)))
+		}
+
+		if got := multiScalarMult(points, scalars); !bytes.Equal(got.Bytes(), want.Bytes()) {
+			t.Errorf("%d points: got %x, want %x", n, got.Bytes(), want.Bytes())
+		}
+	}
+}
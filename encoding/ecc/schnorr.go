@@ -0,0 +1,294 @@
+package ecc
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"filippo.io/bigmod"
+	"filippo.io/nistec"
+)
+
+// Schnorr signatures follow BIP 340, with P-256 in place of secp256k1 and
+// tags of their own.  Public keys are the 32 byte X coordinate of the point
+// with the even Y, and signatures the 32 byte X coordinate of the nonce
+// point R, whose Y is even too, followed by the 32 byte s, so that
+// s*G = R + e*P where e is the challenge hash of R, P and the signed hash.
+// Unlike ECDSA, keys and signatures add up, which allows aggregated keys.
+
+// SchnorrPubKeyLen is the length of a Schnorr public key
+const SchnorrPubKeyLen = 32
+
+// SchnorrSignatureLen is the length of a Schnorr signature
+const SchnorrSignatureLen = 64
+
+// Tags of the hashes, so that a hash computed for one purpose is never
+// valid for another
+const (
+	nonceTag     = "TCN/nonce"
+	challengeTag = "TCN/challenge"
+)
+
+// Return SHA-256(SHA-256(tag) || SHA-256(tag) || data...) as in BIP 340
+func taggedHash(tag string, data ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, d := range data {
+		h.Write(d)
+	}
+
+	return h.Sum(nil)
+}
+
+// Return the private scalar whose point has an even Y, negating d if needed,
+// and the X coordinate of that point
+func evenKey(d *bigmod.Nat) (*bigmod.Nat, []byte) {
+	x, odd, err := pointXY(scalarBaseMult(d))
+	if err != nil {
+		panic(err)
+	}
+
+	return negateIf(d, odd), x
+}
+
+// SchnorrPubKey returns the Schnorr public key of privKey
+func SchnorrPubKey(privKey *ecdsa.PrivateKey) []byte {
+	return privKey.PublicKey.X.FillBytes(make([]byte, scalarLen))
+}
+
+// Return the point with X coordinate x and an even Y
+func liftX(x []byte) (*nistec.P256Point, error) {
+	if len(x) != scalarLen {
+		return nil, fmt.Errorf("%w: %d bytes", ErrInvalidPubKey, len(x))
+	}
+
+	p, err := nistec.NewP256Point().SetBytes(append([]byte{0x02}, x...))
+	if err != nil {
+		return nil, fmt.Errorf("%w: not the X coordinate of a point of the curve", ErrInvalidPubKey)
+	}
+
+	return p, nil
+}
+
+// ParseSchnorrPubKey decodes a Schnorr public key, which must be the X
+// coordinate of a point of the curve
+func ParseSchnorrPubKey(pubKey []byte) (*ecdsa.PublicKey, error) {
+	p, err := liftX(pubKey)
+	if err != nil {
+		return nil, err
+	}
+	xy := p.Bytes()[1:]
+
+	return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(xy[:scalarLen]), Y: new(big.Int).SetBytes(xy[scalarLen:])}, nil
+}
+
+func challenge(r, pubKey, hash []byte) *bigmod.Nat {
+	return hashScalar(taggedHash(challengeTag, r, pubKey, hash))
+}
+
+// SchnorrSign signs hash with privKey.  The nonce is derived from the key
+// and the hash, so signing is repeatable.
+func SchnorrSign(privKey *ecdsa.PrivateKey, hash []byte) ([]byte, error) {
+	d, err := privateScalar(privKey)
+	if err != nil {
+		return nil, err
+	}
+	d, pubKey := evenKey(d)
+
+	k := hashScalar(taggedHash(nonceTag, scalarBytes(d), pubKey, hash))
+	if k.IsZero() == 1 {
+		return nil, errors.New("derived a zero nonce")
+	}
+	k, r := evenKey(k)
+
+	// s = k + e*d mod n
+	s := challenge(r, pubKey, hash).Mul(d, order).Add(k, order)
+
+	return append(r, scalarBytes(s)...), nil
+}
+
+// SchnorrVerify checks a Schnorr signature of hash by pubKey
+func SchnorrVerify(pubKey, hash, signature []byte) error {
+	p, err := liftX(pubKey)
+	if err != nil {
+		return err
+	}
+	if len(signature) != SchnorrSignatureLen {
+		return fmt.Errorf("%w: %d bytes", ErrInvalidSignature, len(signature))
+	}
+	s, err := parseScalar(signature[scalarLen:])
+	if err != nil {
+		return fmt.Errorf("%w: s is not below the curve order", ErrInvalidSignature)
+	}
+
+	// R = s*G - e*P must have an even Y and the signature's X
+	negE := negateIf(challenge(signature[:scalarLen], pubKey, hash), 1)
+	r := scalarBaseMult(s)
+	r.Add(r, scalarMult(p, negE))
+
+	rx, odd, err := pointXY(r)
+	if err != nil || odd == 1 || !bytes.Equal(rx, signature[:scalarLen]) {
+		return errors.New("signature does not match")
+	}
+
+	return nil
+}
+
+// SchnorrVerifier queues Schnorr signatures and checks them together with
+// BIP 340 batch verification: for random scalars a_i, the sum of the
+// a_i*s_i*G must equal that of the a_i*R_i + a_i*e_i*P_i, which holds for
+// valid signatures and, but for a negligible chance, fails if any is
+// invalid.  The sums are computed with one multi-scalar multiplication per
+// CPU, in parallel.  A failed batch is checked signature by signature to
+// find the invalid one.
+type SchnorrVerifier struct {
+	entries []verifierEntry
+}
+
+type verifierEntry struct {
+	pubKey, hash, signature []byte
+}
+
+// VerifierError reports an invalid signature queued on a SchnorrVerifier
+type VerifierError struct {
+	// Index is the position of the signature in the order of Add
+	Index int
+	Err   error
+}
+
+func (e *VerifierError) Error() string {
+	return fmt.Sprintf("signature %d: %v", e.Index, e.Err)
+}
+
+func (e *VerifierError) Unwrap() error {
+	return e.Err
+}
+
+// Add queues a signature of hash by pubKey
+func (v *SchnorrVerifier) Add(pubKey, hash, signature []byte) {
+	v.entries = append(v.entries, verifierEntry{pubKey, hash, signature})
+}
+
+// Len returns the number of queued signatures
+func (v *SchnorrVerifier) Len() int {
+	return len(v.entries)
+}
+
+// Verify checks every queued signature, returning a *VerifierError for the
+// first invalid one in the order of Add
+func (v *SchnorrVerifier) Verify() error {
+	if len(v.entries) == 0 || v.batchHolds() {
+		return nil
+	}
+
+	errs := make([]error, len(v.entries))
+	parallel(len(v.entries), func(start, end int) {
+		for i := start; i < end; i++ {
+			e := v.entries[i]
+			errs[i] = SchnorrVerify(e.pubKey, e.hash, e.signature)
+		}
+	})
+
+	for i, err := range errs {
+		if err != nil {
+			return &VerifierError{Index: i, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// Report whether the batch equation holds for the queued signatures.  It
+// does not if one fails to decode or if no random scalars can be drawn.
+func (v *SchnorrVerifier) batchHolds() bool {
+	coefs := make([]byte, len(v.entries)*scalarLen)
+	if _, err := rand.Read(coefs); err != nil {
+		return false
+	}
+
+	// each range of entries sums its a_i*s_i and a_i*R_i + a_i*e_i*P_i,
+	// stored at the index of its first entry
+	sums := make([]*bigmod.Nat, len(v.entries))
+	points := make([]*nistec.P256Point, len(v.entries))
+	var failed atomic.Bool
+	parallel(len(v.entries), func(start, end int) {
+		sum := bigmod.NewNat().ExpandFor(order)
+		var terms []*nistec.P256Point
+		var scalars [][]byte
+		for i := start; i < end; i++ {
+			a := hashScalar(coefs[i*scalarLen : (i+1)*scalarLen])
+			r, p, as, ae, err := v.entries[i].decode(a)
+			if err != nil {
+				failed.Store(true)
+				return
+			}
+			sum.Add(as, order)
+			terms = append(terms, r, p)
+			scalars = append(scalars, scalarBytes(a), scalarBytes(ae))
+		}
+		sums[start], points[start] = sum, multiScalarMult(terms, scalars)
+	})
+	if failed.Load() {
+		return false
+	}
+
+	sum := bigmod.NewNat().ExpandFor(order)
+	point := nistec.NewP256Point()
+	for i := range points {
+		if points[i] != nil {
+			sum.Add(sums[i], order)
+			point.Add(point, points[i])
+		}
+	}
+
+	return bytes.Equal(scalarBaseMult(sum).Bytes(), point.Bytes())
+}
+
+// Decode the nonce point R, the public key P and s of the entry, returning
+// them with a*s and a*e for its challenge e
+func (e verifierEntry) decode(a *bigmod.Nat) (r, p *nistec.P256Point, as, ae *bigmod.Nat, err error) {
+	if len(e.signature) != SchnorrSignatureLen {
+		return nil, nil, nil, nil, ErrInvalidSignature
+	}
+	if p, err = liftX(e.pubKey); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if r, err = liftX(e.signature[:scalarLen]); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	s, err := parseScalar(e.signature[scalarLen:])
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	return r, p, s.Mul(a, order), challenge(e.signature[:scalarLen], e.pubKey, e.hash).Mul(a, order), nil
+}
+
+// Call work over n items split into one contiguous range per CPU, in
+// parallel
+func parallel(n int, work func(start, end int)) {
+	workers := runtime.NumCPU()
+	if workers > n {
+		workers = n
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			work(start, end)
+		}(w*n/workers, (w+1)*n/workers)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,242 @@
+package ecc
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// Return the key with the given hex encoded scalar
+func testKey(t *testing.T, d string) *ecdsa.PrivateKey {
+	t.Helper()
+
+	scalar := hexInt(t, d)
+	x, y := curve.ScalarBaseMult(intBytes(scalar))
+
+	return &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: curve, X: x, Y: y}, D: scalar}
+}
+
+// Return v as a 32 byte big-endian scalar
+func intBytes(v *big.Int) []byte {
+	return v.FillBytes(make([]byte, scalarLen))
+}
+
+func hexBytes(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+// Return an X coordinate of no point of the curve
+func offCurveX(t *testing.T) []byte {
+	t.Helper()
+
+	for i := int64(1); i < 1000; i++ {
+		x := intBytes(big.NewInt(i))
+		if px, _ := elliptic.UnmarshalCompressed(curve, append([]byte{0x02}, x...)); px == nil {
+			return x
+		}
+	}
+	t.Fatal("no X coordinate off the curve found")
+
+	return nil
+}
+
+// The secret keys and messages of the BIP 340 signing vectors 0 to 3, with
+// the public keys and signatures they give on P-256 with the tags of TCN
+var schnorrVectors = []struct {
+	key, pubKey, hash, signature string
+}{
+	{
+		"0000000000000000000000000000000000000000000000000000000000000003",
+		"5ecbe4d1a6330a44c8f7ef951d4bf165e6c6b721efada985fb41661bc6e7fd6c",
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"29ecb0eb4008596e9262b60acf7e62d164a660bcfd0ded53e33e3d4a3c0a55f1d609b9c3f72209cb7cc92912b26bfe6b300fc8854131b610dd4746dcd4ad2ba8",
+	},
+	{
+		"B7E151628AED2A6ABF7158809CF4F3C762E7160F38B4DA56A784D9045190CFEF",
+		"91f91fd2a3c8010e319c70f2a229bb1b1c6ec80a70d684ea7417dc3c557e5755",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"8318efe2f4aaded0fdc3aed65284db0aea923cc13c528ed9a7b992fbfb21c4559abafd00fc511de0f497fb0dc6e1a420e0991ea2e390eb4e6d8027a87d5e567b",
+	},
+	{
+		"C90FDBAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B14E5C",
+		"a1e66a342fdec4f26b129ebc676d121e9e2226b6167a9128d1e79a996bec9f07",
+		"7E2D58D8B3BCDF1ABADEC7829054F90DDA9805AAB56C77333024B9D0A508B75C",
+		"4771880ef9fe7880a03ba80dda25a77780bc5fa96ea29f06db8e946030dfa50d53671fda3feebb90b8004dda0e97ca014662ebb416cd96ca3de7095630e7abf0",
+	},
+	{
+		"0B432B2677937381AEF05BB02A66ECD012773062CF3FA2549E44F58ED2401710",
+		"1908b1f40d3ab9c0ebbed9c9e86d84da21663dd35d61f367190d023b927213e8",
+		"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF",
+		"00bcadbb7021aacbfd051acf0a3a330e723c1fbd1a59289c0e46ea47a92ea39646969a98b698ce602073e5e2fadd79ce989561a9e7ab805bfc301d291b00fec1",
+	},
+}
+
+func TestSchnorrSignVectors(t *testing.T) {
+	for i, v := range schnorrVectors {
+		privKey := testKey(t, v.key)
+		hash := hexBytes(t, v.hash)
+
+		pubKey := SchnorrPubKey(privKey)
+		if got := hex.EncodeToString(pubKey); got != v.pubKey {
+			t.Errorf("vector %d: public key %s, want %s", i, got, v.pubKey)
+		}
+
+		signature, err := SchnorrSign(privKey, hash)
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		if got := hex.EncodeToString(signature); got != v.signature {
+			t.Errorf("vector %d: signature %s, want %s", i, got, v.signature)
+		}
+		if err := SchnorrVerify(pubKey, hash, signature); err != nil {
+			t.Errorf("vector %d: %v", i, err)
+		}
+	}
+}
+
+func TestSchnorrSignInvalidKey(t *testing.T) {
+	for _, d := range []*big.Int{new(big.Int), curve.Params().N} {
+		privKey := &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: curve}, D: d}
+		if _, err := SchnorrSign(privKey, make([]byte, 32)); err == nil {
+			t.Errorf("signed with the private key %x", d)
+		}
+	}
+}
+
+// Signatures altered as in the BIP 340 verification vectors must not verify
+func TestSchnorrVerifyRejects(t *testing.T) {
+	v := schnorrVectors[1]
+	pubKey := hexBytes(t, v.pubKey)
+	hash := hexBytes(t, v.hash)
+	signature := hexBytes(t, v.signature)
+
+	otherHash := append([]byte(nil), hash...)
+	otherHash[0] ^= 0x01
+
+	negatedS := append([]byte(nil), signature...)
+	s := new(big.Int).SetBytes(signature[scalarLen:])
+	copy(negatedS[scalarLen:], intBytes(s.Sub(curve.Params().N, s)))
+
+	sAtOrder := append(append([]byte(nil), signature[:scalarLen]...), intBytes(curve.Params().N)...)
+	rOffCurve := append(offCurveX(t), signature[scalarLen:]...)
+
+	tests := []struct {
+		name                    string
+		pubKey, hash, signature []byte
+		err                     error
+	}{
+		{"other message", pubKey, otherHash, signature, nil},
+		{"negated s", pubKey, hash, negatedS, nil},
+		{"r off the curve", pubKey, hash, rOffCurve, nil},
+		{"s equal to the curve order", pubKey, hash, sAtOrder, ErrInvalidSignature},
+		{"public key off the curve", offCurveX(t), hash, signature, ErrInvalidPubKey},
+		{"short signature", pubKey, hash, signature[:SchnorrSignatureLen-1], ErrInvalidSignature},
+		{"short public key", pubKey[1:], hash, signature, ErrInvalidPubKey},
+	}
+
+	for _, tt := range tests {
+		err := SchnorrVerify(tt.pubKey, tt.hash, tt.signature)
+		if err == nil {
+			t.Errorf("%s: the signature verified", tt.name)
+		} else if tt.err != nil && !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestSchnorrVerifier(t *testing.T) {
+	var verifier SchnorrVerifier
+	if err := verifier.Verify(); err != nil {
+		t.Fatalf("empty verifier: %v", err)
+	}
+
+	for _, v := range schnorrVectors {
+		verifier.Add(hexBytes(t, v.pubKey), hexBytes(t, v.hash), hexBytes(t, v.signature))
+	}
+	if verifier.Len() != len(schnorrVectors) {
+		t.Fatalf("%d signatures queued, want %d", verifier.Len(), len(schnorrVectors))
+	}
+	if err := verifier.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the signature of vector 0 does not sign the message of vector 2
+	verifier.Add(hexBytes(t, schnorrVectors[0].pubKey), hexBytes(t, schnorrVectors[2].hash), hexBytes(t, schnorrVectors[0].signature))
+	verifier.Add(hexBytes(t, schnorrVectors[1].pubKey), hexBytes(t, schnorrVectors[1].hash), hexBytes(t, schnorrVectors[1].signature)[1:])
+
+	var verifierErr *VerifierError
+	if err := verifier.Verify(); !errors.As(err, &verifierErr) {
+		t.Fatalf("got %v, want a *VerifierError", err)
+	}
+	if verifierErr.Index != len(schnorrVectors) {
+		t.Fatalf("invalid signature reported at index %d, want %d", verifierErr.Index, len(schnorrVectors))
+	}
+	if !bytes.Equal(hexBytes(t, schnorrVectors[0].signature), verifier.entries[verifierErr.Index].signature) {
+		t.Fatal("the reported index does not match the order of Add")
+	}
+}
+
+// Two invalid signatures whose errors cancel out pass a batch that sums the
+// equations as they are, but not one weighted by random scalars
+func TestSchnorrVerifierCancellingSignatures(t *testing.T) {
+	n := curve.Params().N
+	shift := func(signature []byte, delta int64) []byte {
+		s := new(big.Int).SetBytes(signature[scalarLen:])
+		s.Add(s, big.NewInt(delta)).Mod(s, n)
+		return append(append([]byte{}, signature[:scalarLen]...), intBytes(s)...)
+	}
+
+	var verifier SchnorrVerifier
+	for i, v := range schnorrVectors[:2] {
+		signature := shift(hexBytes(t, v.signature), int64(1-2*i))
+		verifier.Add(hexBytes(t, v.pubKey), hexBytes(t, v.hash), signature)
+	}
+	if verifier.batchHolds() {
+		t.Fatal("the batch equation holds")
+	}
+
+	var verifierErr *VerifierError
+	if err := verifier.Verify(); !errors.As(err, &verifierErr) || verifierErr.Index != 0 {
+		t.Fatalf("got %v, want a *VerifierError for signature 0", err)
+	}
+}
+
+func TestSchnorrVerifierBatch(t *testing.T) {
+	var verifier SchnorrVerifier
+	for i := 1; i <= 50; i++ {
+		privKey := testKey(t, fmt.Sprintf("%064x", i))
+		hash := bytes.Repeat([]byte{byte(i)}, 32)
+		signature, err := SchnorrSign(privKey, hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		verifier.Add(SchnorrPubKey(privKey), hash, signature)
+	}
+	if !verifier.batchHolds() {
+		t.Fatal("the batch equation does not hold for valid signatures")
+	}
+
+	// a signature over a different hash is found wherever it is
+	for _, index := range []int{0, 23, 49} {
+		entry := verifier.entries[index]
+		verifier.entries[index].hash = make([]byte, 32)
+
+		var verifierErr *VerifierError
+		if err := verifier.Verify(); !errors.As(err, &verifierErr) || verifierErr.Index != index {
+			t.Fatalf("got %v, want a *VerifierError for signature %d", err, index)
+		}
+		verifier.entries[index] = entry
+	}
+}
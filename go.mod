@@ -0,0 +1,11 @@
+module github.com/jplesperance/tcn
+
+go 1.21
+
+require (
+	filippo.io/bigmod v0.0.1
+	filippo.io/nistec v0.0.3
+	github.com/boltdb/bolt v1.3.2-0.20180302180052-fd01fc79c553
+	golang.org/x/crypto v0.0.0-20180330210355-12892e8c234f
+	golang.org/x/sys v0.0.0-20180329131831-378d26f46672
+)
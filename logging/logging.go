@@ -0,0 +1,198 @@
+// Package logging provides leveled, structured loggers tagged with the
+// subsystem they belong to
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Subsystems tagging every log record
+const (
+	Chain  = "chain"
+	PoW    = "pow"
+	Wallet = "wallet"
+	Net    = "net"
+	RPC    = "rpc"
+)
+
+// Subsystems lists the known subsystems
+var Subsystems = []string{Chain, PoW, Wallet, Net, RPC}
+
+// Output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Options configures where and how records are written
+//
+// Level: records below this level are dropped
+// Subsystems: per subsystem levels overriding Level
+// Format: FormatText or FormatJSON
+// Output: destination of the records, os.Stderr if nil
+type Options struct {
+	Level      slog.Level
+	Subsystems map[string]slog.Level
+	Format     string
+	Output     io.Writer
+}
+
+type config struct {
+	opts   Options
+	logger *slog.Logger
+}
+
+var current atomic.Pointer[config]
+
+// Until Configure is called, records at info level and above are written
+// to os.Stderr as text
+func init() {
+	current.Store(&config{Options{Level: slog.LevelInfo, Format: FormatText}, slog.New(slog.NewTextHandler(os.Stderr, filterAll))})
+}
+
+// Filtering is done per subsystem, so handlers accept every record
+var filterAll = &slog.HandlerOptions{Level: slog.LevelDebug}
+
+// Configure replaces the logging configuration of every subsystem
+func Configure(opts Options) error {
+	output := opts.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	var handler slog.Handler
+	switch opts.Format {
+	case FormatText, "":
+		handler = slog.NewTextHandler(output, filterAll)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(output, filterAll)
+	default:
+		return fmt.Errorf("unknown log format %q, expected %s or %s", opts.Format, FormatText, FormatJSON)
+	}
+
+	for subsystem := range opts.Subsystems {
+		if !knownSubsystem(subsystem) {
+			return fmt.Errorf("unknown log subsystem %q, expected one of: %s", subsystem, strings.Join(Subsystems, ", "))
+		}
+	}
+
+	current.Store(&config{opts, slog.New(handler)})
+
+	return nil
+}
+
+// ParseLevels parses a level specification such as "info" or
+// "warn,pow=debug,chain=info": a default level optionally followed by
+// comma separated subsystem=level overrides
+func ParseLevels(spec string) (slog.Level, map[string]slog.Level, error) {
+	level := slog.LevelInfo
+	subsystems := make(map[string]slog.Level)
+
+	for i, part := range strings.Split(spec, ",") {
+		name, value, override := strings.Cut(part, "=")
+		if !override {
+			if i != 0 {
+				return 0, nil, fmt.Errorf("invalid log level %q: only the first level may omit a subsystem", part)
+			}
+			value = name
+		}
+
+		parsed, err := parseLevel(value)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if override {
+			if !knownSubsystem(name) {
+				return 0, nil, fmt.Errorf("unknown log subsystem %q, expected one of: %s", name, strings.Join(Subsystems, ", "))
+			}
+			subsystems[name] = parsed
+		} else {
+			level = parsed
+		}
+	}
+
+	return level, subsystems, nil
+}
+
+func parseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+
+	return 0, fmt.Errorf("unknown log level %q, expected debug, info, warn or error", s)
+}
+
+func knownSubsystem(name string) bool {
+	for _, subsystem := range Subsystems {
+		if subsystem == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Logger writes records tagged with a subsystem using the configuration
+// current at the time of each call
+type Logger struct {
+	subsystem string
+}
+
+// New returns the logger for subsystem
+func New(subsystem string) *Logger {
+	return &Logger{subsystem}
+}
+
+// Enabled reports whether records at level are written
+func (l *Logger) Enabled(level slog.Level) bool {
+	opts := current.Load().opts
+	min, ok := opts.Subsystems[l.subsystem]
+	if !ok {
+		min = opts.Level
+	}
+
+	return level >= min
+}
+
+// Debug logs msg with the given key-value pairs at debug level
+func (l *Logger) Debug(msg string, args ...any) {
+	l.log(slog.LevelDebug, msg, args)
+}
+
+// Info logs msg with the given key-value pairs at info level
+func (l *Logger) Info(msg string, args ...any) {
+	l.log(slog.LevelInfo, msg, args)
+}
+
+// Warn logs msg with the given key-value pairs at warn level
+func (l *Logger) Warn(msg string, args ...any) {
+	l.log(slog.LevelWarn, msg, args)
+}
+
+// Error logs msg with the given key-value pairs at error level
+func (l *Logger) Error(msg string, args ...any) {
+	l.log(slog.LevelError, msg, args)
+}
+
+func (l *Logger) log(level slog.Level, msg string, args []any) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	args = append([]any{"subsystem", l.subsystem}, args...)
+	current.Load().logger.Log(context.Background(), level, msg, args...)
+}
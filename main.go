@@ -1,8 +0,0 @@
-package main
-
-// Meat of the app
-func main() {
-
-	cli := CLI{}
-	cli.Run()
-}
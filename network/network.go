@@ -0,0 +1,128 @@
+// Package network defines the parameters that differ between networks
+package network
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Checkpoint pins the hash of the block at a height.  A chain whose block at
+// that height has a different hash is rejected, so no fork below the
+// checkpoint can be accepted.
+type Checkpoint struct {
+	Height int
+	Hash   []byte
+}
+
+// Params holds the parameters of a network
+//
+// Name: the name selecting the network on the command line
+// Checkpoints: hard-coded checkpoints in increasing height order
+// AssumeValid: hash of a block whose ancestors' signatures are assumed
+// valid when importing the chain, nil to verify every signature
+// PrivateKeyPrefix: version byte of exported private keys, so that a key
+// cannot be imported into a wallet of another network
+// MaxBlockSize: maximum size of a serialized block in bytes
+// MaxBlockSigOps: maximum number of signature checks needed to validate
+// the transactions of a block
+// DBFile: default name of the chain database file
+// WalletFile: default name of the wallet file
+// LegacyGenesis: whether the genesis block is the legacy block the chain
+// started from rather than one built from GenesisTime and GenesisNonce
+// GenesisTime: timestamp of the genesis block
+// GenesisNonce: proof of work nonce of the genesis block
+// MaxFutureBlockTime: how far ahead of the node's clock a block timestamp
+// may be
+type Params struct {
+	Name               string
+	Checkpoints        []Checkpoint
+	AssumeValid        []byte
+	PrivateKeyPrefix   byte
+	MaxBlockSize       int
+	MaxBlockSigOps     int
+	DBFile             string
+	WalletFile         string
+	LegacyGenesis      bool
+	GenesisTime        int64
+	GenesisNonce       int
+	MaxFutureBlockTime time.Duration
+}
+
+// Main is the main network.  Its genesis block is the legacy block the
+// chain started from; checkpoints are added as its chain grows.
+var Main = Params{
+	Name: "main",
+	Checkpoints: []Checkpoint{
+		{0, mustDecodeHex("000004980be7bf4f809e75366209d595d9df7b064b06304fa83b5235b11d23b7")},
+	},
+	PrivateKeyPrefix:   0x80,
+	MaxBlockSize:       1000000,
+	MaxBlockSigOps:     20000,
+	DBFile:             "blockchain.db",
+	WalletFile:         "wallet.dat",
+	LegacyGenesis:      true,
+	MaxFutureBlockTime: 2 * time.Hour,
+}
+
+// Test is the test network, whose only checkpoint is its genesis block
+var Test = Params{
+	Name: "test",
+	Checkpoints: []Checkpoint{
+		{0, mustDecodeHex("000007ccf8d5b1f953bee23f4af9935c5abe5443971ed046e9ee5a9c1defa3c7")},
+	},
+	PrivateKeyPrefix:   0xef,
+	MaxBlockSize:       1000000,
+	MaxBlockSigOps:     20000,
+	DBFile:             "blockchain-test.db",
+	WalletFile:         "wallet-test.dat",
+	GenesisTime:        1760832000,
+	GenesisNonce:       6387150,
+	MaxFutureBlockTime: 2 * time.Hour,
+}
+
+var networks = []*Params{&Main, &Test}
+
+// ByName returns the parameters of the network called name
+func ByName(name string) (*Params, error) {
+	var names []string
+	for _, params := range networks {
+		if params.Name == name {
+			return params, nil
+		}
+		names = append(names, params.Name)
+	}
+
+	return nil, fmt.Errorf("unknown network %q, expected one of: %s", name, strings.Join(names, ", "))
+}
+
+// Checkpoint returns the checkpointed hash at height, or nil if the height
+// is not checkpointed
+func (p *Params) Checkpoint(height int) []byte {
+	for _, checkpoint := range p.Checkpoints {
+		if checkpoint.Height == height {
+			return checkpoint.Hash
+		}
+	}
+
+	return nil
+}
+
+// LastCheckpoint returns the highest checkpoint, or nil if there are none
+func (p *Params) LastCheckpoint() *Checkpoint {
+	if len(p.Checkpoints) == 0 {
+		return nil
+	}
+
+	return &p.Checkpoints[len(p.Checkpoints)-1]
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
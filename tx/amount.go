@@ -0,0 +1,155 @@
+package tx
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Amount is a quantity of coins expressed in the smallest indivisible unit
+type Amount int64
+
+// Number of decimal places a coin can be divided into
+const amountDecimals = 8
+
+// Coin is the number of sub-units in one coin
+const Coin Amount = 100000000
+
+// MaxMoney is the largest amount that may appear in a single output or as
+// the total of a transaction's inputs or outputs
+const MaxMoney Amount = 21000000 * Coin
+
+var (
+	ErrNegativeAmount  = errors.New("amount is negative")
+	ErrAmountOverflow  = errors.New("amount exceeds the maximum money supply")
+	ErrInvalidAmount   = errors.New("amount is not a valid number of coins")
+	errAmountPrecision = fmt.Errorf("amount has more than %d decimal places", amountDecimals)
+)
+
+// Check that the amount lies within 0 and MaxMoney
+func (a Amount) Validate() error {
+	if a < 0 {
+		return ErrNegativeAmount
+	}
+	if a > MaxMoney {
+		return ErrAmountOverflow
+	}
+
+	return nil
+}
+
+// Add returns a+b, failing if either operand or the result is out of range
+func (a Amount) Add(b Amount) (Amount, error) {
+	if err := a.Validate(); err != nil {
+		return 0, err
+	}
+	if err := b.Validate(); err != nil {
+		return 0, err
+	}
+
+	// both operands are at most MaxMoney so the sum cannot wrap
+	sum := a + b
+	if sum > MaxMoney {
+		return 0, ErrAmountOverflow
+	}
+
+	return sum, nil
+}
+
+// Sub returns a-b, failing if either operand is out of range or b exceeds a
+func (a Amount) Sub(b Amount) (Amount, error) {
+	if err := a.Validate(); err != nil {
+		return 0, err
+	}
+	if err := b.Validate(); err != nil {
+		return 0, err
+	}
+	if b > a {
+		return 0, ErrNegativeAmount
+	}
+
+	return a - b, nil
+}
+
+// SumAmounts adds amounts with overflow checking
+func SumAmounts(amounts ...Amount) (Amount, error) {
+	var total Amount
+	for _, amount := range amounts {
+		var err error
+		total, err = total.Add(amount)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return total, nil
+}
+
+// Format the amount in coins, e.g. 150000000 as "1.5"
+func (a Amount) String() string {
+	sign := ""
+	value := uint64(a)
+	if a < 0 {
+		sign = "-"
+		value = uint64(-a)
+	}
+
+	whole := value / uint64(Coin)
+	frac := value % uint64(Coin)
+	if frac == 0 {
+		return fmt.Sprintf("%s%d", sign, whole)
+	}
+
+	fracStr := strings.TrimRight(fmt.Sprintf("%0*d", amountDecimals, frac), "0")
+
+	return fmt.Sprintf("%s%d.%s", sign, whole, fracStr)
+}
+
+// ParseAmount parses a decimal number of coins such as "0.5" into an
+// Amount, rejecting negative values, values above MaxMoney and values more
+// precise than one sub-unit
+func ParseAmount(s string) (Amount, error) {
+	if strings.HasPrefix(s, "-") {
+		return 0, ErrNegativeAmount
+	}
+
+	parts := strings.SplitN(s, ".", 2)
+	if parts[0] == "" && (len(parts) == 1 || parts[1] == "") {
+		return 0, ErrInvalidAmount
+	}
+
+	whole := uint64(0)
+	if parts[0] != "" {
+		var err error
+		whole, err = strconv.ParseUint(parts[0], 10, 64)
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, ErrAmountOverflow
+		}
+		if err != nil {
+			return 0, ErrInvalidAmount
+		}
+		if whole > uint64(MaxMoney/Coin) {
+			return 0, ErrAmountOverflow
+		}
+	}
+
+	frac := uint64(0)
+	if len(parts) == 2 && parts[1] != "" {
+		if len(parts[1]) > amountDecimals {
+			return 0, errAmountPrecision
+		}
+		var err error
+		frac, err = strconv.ParseUint(parts[1]+strings.Repeat("0", amountDecimals-len(parts[1])), 10, 64)
+		if err != nil {
+			return 0, ErrInvalidAmount
+		}
+	}
+
+	amount := Amount(whole)*Coin + Amount(frac)
+	if err := amount.Validate(); err != nil {
+		return 0, err
+	}
+
+	return amount, nil
+}
@@ -0,0 +1,126 @@
+package tx
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAmountAdd(t *testing.T) {
+	tests := []struct {
+		a, b Amount
+		want Amount
+		err  error
+	}{
+		{Coin, 2 * Coin, 3 * Coin, nil},
+		{0, 0, 0, nil},
+		{MaxMoney - 1, 1, MaxMoney, nil},
+		{MaxMoney, 1, 0, ErrAmountOverflow},
+		{MaxMoney, MaxMoney, 0, ErrAmountOverflow},
+		{MaxMoney + 1, 0, 0, ErrAmountOverflow},
+		{0, MaxMoney + 1, 0, ErrAmountOverflow},
+		{-1, 2, 0, ErrNegativeAmount},
+		{2, -1, 0, ErrNegativeAmount},
+		// would wrap to a valid amount without the operand checks
+		{1<<63 - 1, 1<<63 - 1, 0, ErrAmountOverflow},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.a.Add(tt.b)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("%d + %d = %d, %v; want %d, %v", int64(tt.a), int64(tt.b), int64(got), err, int64(tt.want), tt.err)
+		}
+	}
+}
+
+func TestAmountSub(t *testing.T) {
+	tests := []struct {
+		a, b Amount
+		want Amount
+		err  error
+	}{
+		{3 * Coin, Coin, 2 * Coin, nil},
+		{Coin, Coin, 0, nil},
+		{MaxMoney, MaxMoney, 0, nil},
+		{Coin, Coin + 1, 0, ErrNegativeAmount},
+		{Coin, -Coin, 0, ErrNegativeAmount},
+		{MaxMoney + 1, 1, 0, ErrAmountOverflow},
+		{MaxMoney, MaxMoney + 1, 0, ErrAmountOverflow},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.a.Sub(tt.b)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("%d - %d = %d, %v; want %d, %v", int64(tt.a), int64(tt.b), int64(got), err, int64(tt.want), tt.err)
+		}
+	}
+}
+
+func TestSumAmounts(t *testing.T) {
+	if total, err := SumAmounts(Coin, 2*Coin, 3); total != 3*Coin+3 || err != nil {
+		t.Fatalf("got %d, %v", int64(total), err)
+	}
+	if _, err := SumAmounts(MaxMoney/2, MaxMoney/2, MaxMoney/2); !errors.Is(err, ErrAmountOverflow) {
+		t.Fatalf("got %v, want %v", err, ErrAmountOverflow)
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Amount
+		err  error
+	}{
+		{"1", Coin, nil},
+		{"0.5", Coin / 2, nil},
+		{".5", Coin / 2, nil},
+		{"5.", 5 * Coin, nil},
+		{"0.00000001", 1, nil},
+		{"1.10000000", Coin + Coin/10, nil},
+		{"0", 0, nil},
+		{"21000000", MaxMoney, nil},
+		{"21000000.00000001", 0, ErrAmountOverflow},
+		{"21000001", 0, ErrAmountOverflow},
+		{"99999999999999999999", 0, ErrAmountOverflow},
+		{"0.000000001", 0, errAmountPrecision},
+		{"-1", 0, ErrNegativeAmount},
+		{"", 0, ErrInvalidAmount},
+		{".", 0, ErrInvalidAmount},
+		{"+1", 0, ErrInvalidAmount},
+		{"1.-5", 0, ErrInvalidAmount},
+		{"1e3", 0, ErrInvalidAmount},
+		{"1.2.3", 0, ErrInvalidAmount},
+		{"one", 0, ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.s)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("ParseAmount(%q) = %d, %v; want %d, %v", tt.s, int64(got), err, int64(tt.want), tt.err)
+		}
+	}
+}
+
+func TestAmountString(t *testing.T) {
+	tests := []struct {
+		amount Amount
+		want   string
+	}{
+		{0, "0"},
+		{Coin, "1"},
+		{Coin + Coin/2, "1.5"},
+		{1, "0.00000001"},
+		{MaxMoney, "21000000"},
+		{-Coin / 4, "-0.25"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.amount.String(); got != tt.want {
+			t.Errorf("%d formats as %q, want %q", int64(tt.amount), got, tt.want)
+		}
+		if tt.amount >= 0 {
+			if parsed, err := ParseAmount(tt.want); parsed != tt.amount || err != nil {
+				t.Errorf("%q parses as %d, %v", tt.want, int64(parsed), err)
+			}
+		}
+	}
+}
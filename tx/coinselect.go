@@ -0,0 +1,168 @@
+package tx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maximum number of branches branch-and-bound explores before giving up
+const bnbMaxTries = 100000
+
+// UTXO is a single unspent transaction output together with its outpoint
+//
+// TxID: ID of the transaction holding the output
+// Index: position of the output in the transaction's Vout
+// Output: the output itself
+// Height: height of the block holding the transaction
+type UTXO struct {
+	TxID   []byte
+	Index  int
+	Output TXOutput
+	Height int
+}
+
+// CoinSelector picks the outputs used to fund a transaction
+//
+// Select returns the chosen outputs and their total value.  When the
+// candidates cannot cover amount the returned total is less than amount.
+type CoinSelector interface {
+	Select(candidates []UTXO, amount Amount) ([]UTXO, Amount)
+}
+
+// LargestFirst spends the biggest outputs first, producing the fewest inputs
+type LargestFirst struct{}
+
+// SmallestFirst spends the smallest outputs first, consolidating dust
+type SmallestFirst struct{}
+
+// BranchAndBound searches for a set of outputs matching the amount exactly
+// so that no change output is needed.  If no exact match exists it falls
+// back to Fallback.
+type BranchAndBound struct {
+	Fallback CoinSelector
+}
+
+var coinSelectors = map[string]CoinSelector{
+	"largest":  LargestFirst{},
+	"smallest": SmallestFirst{},
+	"bnb":      BranchAndBound{LargestFirst{}},
+}
+
+// GetCoinSelector returns the coin selection strategy registered under name
+func GetCoinSelector(name string) (CoinSelector, error) {
+	selector, ok := coinSelectors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown coin selection strategy %q, expected one of: %s",
+			name, strings.Join(CoinSelectorNames(), ", "))
+	}
+
+	return selector, nil
+}
+
+// CoinSelectorNames lists the registered coin selection strategies
+func CoinSelectorNames() []string {
+	var names []string
+	for name := range coinSelectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Select implements CoinSelector
+func (LargestFirst) Select(candidates []UTXO, amount Amount) ([]UTXO, Amount) {
+	sorted := sortedByValue(candidates, true)
+
+	return accumulate(sorted, amount)
+}
+
+// Select implements CoinSelector
+func (SmallestFirst) Select(candidates []UTXO, amount Amount) ([]UTXO, Amount) {
+	sorted := sortedByValue(candidates, false)
+
+	return accumulate(sorted, amount)
+}
+
+// Select implements CoinSelector
+//
+// Candidates are explored depth first in descending value order, pruning
+// any branch that overshoots the amount or can no longer reach it.
+func (bnb BranchAndBound) Select(candidates []UTXO, amount Amount) ([]UTXO, Amount) {
+	sorted := sortedByValue(candidates, true)
+
+	// remaining[i] holds the total value of sorted[i:]
+	remaining := make([]Amount, len(sorted)+1)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + sorted[i].Output.Value
+	}
+
+	var selected []int
+	tries := 0
+
+	var search func(depth int, total Amount) bool
+	search = func(depth int, total Amount) bool {
+		tries++
+		if total == amount {
+			return true
+		}
+		if total > amount || total+remaining[depth] < amount || depth == len(sorted) || tries > bnbMaxTries {
+			return false
+		}
+
+		selected = append(selected, depth)
+		if search(depth+1, total+sorted[depth].Output.Value) {
+			return true
+		}
+		selected = selected[:len(selected)-1]
+
+		return search(depth+1, total)
+	}
+
+	if amount > 0 && search(0, 0) {
+		var result []UTXO
+		for _, i := range selected {
+			result = append(result, sorted[i])
+		}
+		return result, amount
+	}
+
+	fallback := bnb.Fallback
+	if fallback == nil {
+		fallback = LargestFirst{}
+	}
+
+	return fallback.Select(candidates, amount)
+}
+
+// Return a copy of the candidates sorted by value
+func sortedByValue(candidates []UTXO, descending bool) []UTXO {
+	sorted := make([]UTXO, len(candidates))
+	copy(sorted, candidates)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if descending {
+			return sorted[i].Output.Value > sorted[j].Output.Value
+		}
+		return sorted[i].Output.Value < sorted[j].Output.Value
+	})
+
+	return sorted
+}
+
+// Take outputs in order until amount is covered
+func accumulate(sorted []UTXO, amount Amount) ([]UTXO, Amount) {
+	var selected []UTXO
+	var accumulated Amount
+
+	for _, utxo := range sorted {
+		if accumulated >= amount {
+			break
+		}
+		selected = append(selected, utxo)
+		accumulated += utxo.Output.Value
+	}
+
+	return selected, accumulated
+}
@@ -0,0 +1,91 @@
+package tx
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// Return one candidate output per value, indexed in the order given
+func utxos(values ...Amount) []UTXO {
+	var candidates []UTXO
+	for i, value := range values {
+		candidates = append(candidates, UTXO{TxID: []byte{byte(i)}, Index: i, Output: TXOutput{Value: value}})
+	}
+
+	return candidates
+}
+
+// Return the sorted values of the selected outputs
+func values(selected []UTXO) []Amount {
+	var values []Amount
+	for _, utxo := range selected {
+		values = append(values, utxo.Output.Value)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	return values
+}
+
+func TestCoinSelectors(t *testing.T) {
+	tests := []struct {
+		name       string
+		selector   CoinSelector
+		candidates []UTXO
+		amount     Amount
+		want       []Amount
+		total      Amount
+	}{
+		{"largest first", LargestFirst{}, utxos(1, 5, 3), 4, []Amount{5}, 5},
+		{"largest first takes several", LargestFirst{}, utxos(1, 5, 3), 7, []Amount{3, 5}, 8},
+		{"smallest first", SmallestFirst{}, utxos(5, 1, 3), 4, []Amount{1, 3}, 4},
+		{"smallest first overshoots", SmallestFirst{}, utxos(5, 1, 3), 5, []Amount{1, 3, 5}, 9},
+		{"insufficient", LargestFirst{}, utxos(1, 2), 4, []Amount{1, 2}, 3},
+		{"no candidates", SmallestFirst{}, nil, 1, nil, 0},
+		{"bnb exact single", BranchAndBound{}, utxos(7, 3, 2), 3, []Amount{3}, 3},
+		{"bnb exact pair", BranchAndBound{}, utxos(8, 5, 4, 1), 9, []Amount{1, 8}, 9},
+		{"bnb skips the largest", BranchAndBound{}, utxos(10, 6, 3, 1), 4, []Amount{1, 3}, 4},
+		{"bnb falls back to largest", BranchAndBound{}, utxos(10, 6, 3), 4, []Amount{10}, 10},
+		{"bnb falls back to smallest", BranchAndBound{SmallestFirst{}}, utxos(10, 6, 3), 4, []Amount{3, 6}, 9},
+		{"bnb insufficient", BranchAndBound{}, utxos(1, 2), 4, []Amount{1, 2}, 3},
+		{"bnb zero amount", BranchAndBound{}, utxos(1, 2), 0, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, total := tt.selector.Select(tt.candidates, tt.amount)
+			if got := values(selected); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selected %v, want %v", got, tt.want)
+			}
+			if total != tt.total {
+				t.Errorf("total %d, want %d", total, tt.total)
+			}
+		})
+	}
+}
+
+// Selection must not reorder the caller's candidates
+func TestCoinSelectorsKeepCandidates(t *testing.T) {
+	candidates := utxos(1, 5, 3)
+	original := append([]UTXO(nil), candidates...)
+
+	for _, name := range CoinSelectorNames() {
+		selector, err := GetCoinSelector(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		selector.Select(candidates, 4)
+		if !reflect.DeepEqual(candidates, original) {
+			t.Fatalf("%s reordered the candidates", name)
+		}
+	}
+}
+
+func TestGetCoinSelector(t *testing.T) {
+	if names := CoinSelectorNames(); !reflect.DeepEqual(names, []string{"bnb", "largest", "smallest"}) {
+		t.Fatalf("strategies %v", names)
+	}
+	if _, err := GetCoinSelector("random"); err == nil {
+		t.Fatal("got a selector for an unknown strategy")
+	}
+}
@@ -0,0 +1,25 @@
+package tx
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by the transaction API.  Callers can test for them with
+// errors.Is.
+var (
+	ErrTxNotFound         = errors.New("transaction is not found")
+	ErrInvalidTransaction = errors.New("invalid transaction")
+	ErrInvalidSignature   = errors.New("invalid signature")
+)
+
+// InvalidSignatureError reports a transaction whose signatures do not verify
+type InvalidSignatureError struct {
+	Tx []byte
+}
+
+func (e *InvalidSignatureError) Error() string {
+	return fmt.Sprintf("transaction %x has an invalid signature", e.Tx)
+}
+
+func (e *InvalidSignatureError) Unwrap() error { return ErrInvalidSignature }
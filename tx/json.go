@@ -0,0 +1,82 @@
+package tx
+
+import (
+	"encoding/hex"
+	"encoding/json"
+)
+
+// JSON representations use snake_case keys and hex encode byte fields.
+// Amounts are written as decimal numbers of coins.
+
+type txInputJSON struct {
+	Txid      string `json:"txid"`
+	Vout      int    `json:"vout"`
+	Signature string `json:"signature"`
+	PubKey    string `json:"pubkey"`
+}
+
+type txOutputJSON struct {
+	Value      Amount `json:"value"`
+	PubKeyHash string `json:"pubkey_hash"`
+	Type       string `json:"type"`
+}
+
+type transactionJSON struct {
+	Version  int        `json:"version"`
+	ID       string     `json:"id"`
+	Coinbase bool       `json:"coinbase"`
+	Vin      []TXInput  `json:"vin"`
+	Vout     []TXOutput `json:"vout"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting a decimal number of
+// coins
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	amount, err := ParseAmount(string(data))
+	if err != nil {
+		return err
+	}
+	*a = amount
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (in TXInput) MarshalJSON() ([]byte, error) {
+	return json.Marshal(txInputJSON{
+		Txid:      hex.EncodeToString(in.Txid),
+		Vout:      in.Vout,
+		Signature: hex.EncodeToString(in.Signature),
+		PubKey:    hex.EncodeToString(in.PubKey),
+	})
+}
+
+// MarshalJSON implements json.Marshaler
+func (out TXOutput) MarshalJSON() ([]byte, error) {
+	return json.Marshal(txOutputJSON{out.Value, hex.EncodeToString(out.PubKeyHash), out.Type.String()})
+}
+
+// MarshalJSON implements json.Marshaler
+func (tx Transaction) MarshalJSON() ([]byte, error) {
+	vin := tx.Vin
+	if vin == nil {
+		vin = []TXInput{}
+	}
+	vout := tx.Vout
+	if vout == nil {
+		vout = []TXOutput{}
+	}
+
+	return json.Marshal(transactionJSON{
+		Version:  tx.Version,
+		ID:       hex.EncodeToString(tx.ID),
+		Coinbase: tx.IsCoinbase(),
+		Vin:      vin,
+		Vout:     vout,
+	})
+}
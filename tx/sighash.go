@@ -0,0 +1,190 @@
+package tx
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/jplesperance/tcn/encoding/canonical"
+	"github.com/jplesperance/tcn/encoding/ecc"
+)
+
+// SigHashType selects the inputs and outputs of a transaction that a
+// signature commits to.  It is appended to each input's signature.
+type SigHashType byte
+
+// Signature hash types.  SigHashAnyoneCanPay is a flag combined with one of
+// the others.
+const (
+	// SigHashAll commits to every input and output
+	SigHashAll SigHashType = 0x01
+	// SigHashNone commits to every input and no output, so the outputs can
+	// be chosen by whoever completes the transaction
+	SigHashNone SigHashType = 0x02
+	// SigHashSingle commits to every input and the output with the same
+	// index as the signed input
+	SigHashSingle SigHashType = 0x03
+	// SigHashAnyoneCanPay commits to the signed input only, so others can
+	// add inputs, e.g. to fund a crowdfunding output together
+	SigHashAnyoneCanPay SigHashType = 0x80
+)
+
+// Tag prefixed to every signature hash, so that it cannot collide with the
+// hash of anything else signed with the same key
+const sigHashTag = "TCN sighash"
+
+var sigHashNames = map[SigHashType]string{
+	SigHashAll:    "ALL",
+	SigHashNone:   "NONE",
+	SigHashSingle: "SINGLE",
+}
+
+func (t SigHashType) base() SigHashType {
+	return t &^ SigHashAnyoneCanPay
+}
+
+// Valid reports whether t is one of the defined types
+func (t SigHashType) Valid() bool {
+	_, ok := sigHashNames[t.base()]
+	return ok
+}
+
+func (t SigHashType) String() string {
+	name, ok := sigHashNames[t.base()]
+	if !ok {
+		return fmt.Sprintf("0x%02x", byte(t))
+	}
+	if t&SigHashAnyoneCanPay != 0 {
+		name += "|ANYONECANPAY"
+	}
+
+	return name
+}
+
+// SignatureHash returns the hash signed by input inID under hashType: the
+// double SHA-256 of the canonical encoding of the tag, the transaction
+// version, hashType, the committed inputs, the outpoint, value and public
+// key hash of the output spent by inID, the committed outputs and, for
+// SigHashSingle, inID.  The type of the spent output follows its public key
+// hash and the type of each committed output its public key hash.
+func (tx *Transaction) SignatureHash(inID int, hashType SigHashType, prevTXs map[string]Transaction) ([]byte, error) {
+	if !hashType.Valid() {
+		return nil, fmt.Errorf("%w: unknown signature hash type %s", ErrInvalidTransaction, hashType)
+	}
+	if inID < 0 || inID >= len(tx.Vin) {
+		return nil, fmt.Errorf("%w: input %d does not exist", ErrInvalidTransaction, inID)
+	}
+	vin := tx.Vin[inID]
+	prevTx := prevTXs[hex.EncodeToString(vin.Txid)]
+	if vin.Vout < 0 || vin.Vout >= len(prevTx.Vout) {
+		return nil, fmt.Errorf("%w: previous output %x:%d", ErrTxNotFound, vin.Txid, vin.Vout)
+	}
+	spent := prevTx.Vout[vin.Vout]
+
+	var e canonical.Encoder
+	e.WriteBytes([]byte(sigHashTag))
+	e.WriteUvarint(uint64(tx.Version))
+	e.WriteUvarint(uint64(hashType))
+
+	inputs := tx.Vin
+	if hashType&SigHashAnyoneCanPay != 0 {
+		inputs = tx.Vin[inID : inID+1]
+	}
+	e.WriteUvarint(uint64(len(inputs)))
+	for _, in := range inputs {
+		e.WriteBytes(in.Txid)
+		e.WriteVarint(int64(in.Vout))
+	}
+
+	e.WriteBytes(vin.Txid)
+	e.WriteVarint(int64(vin.Vout))
+	e.WriteVarint(int64(spent.Value))
+	e.WriteBytes(spent.PubKeyHash)
+	e.WriteUvarint(uint64(spent.Type))
+
+	var outputs []TXOutput
+	switch hashType.base() {
+	case SigHashAll:
+		outputs = tx.Vout
+	case SigHashSingle:
+		if inID >= len(tx.Vout) {
+			return nil, fmt.Errorf("%w: input %d is signed with SINGLE but has no matching output", ErrInvalidTransaction, inID)
+		}
+		outputs = tx.Vout[inID : inID+1]
+	}
+	e.WriteUvarint(uint64(len(outputs)))
+	for _, out := range outputs {
+		e.WriteVarint(int64(out.Value))
+		e.WriteBytes(out.PubKeyHash)
+		e.WriteUvarint(uint64(out.Type))
+	}
+	if hashType.base() == SigHashSingle {
+		e.WriteUvarint(uint64(inID))
+	}
+
+	first := sha256.Sum256(e.Bytes())
+	second := sha256.Sum256(first[:])
+
+	return second[:], nil
+}
+
+// Sign input inID with privKey under hashType, appending the type to the
+// DER signature, or to the Schnorr signature for Schnorr outputs
+func (tx *Transaction) signInput(inID int, privKey *ecdsa.PrivateKey, hashType SigHashType, prevTXs map[string]Transaction) error {
+	hash, err := tx.SignatureHash(inID, hashType, prevTXs)
+	if err != nil {
+		return err
+	}
+	sign := ecc.Sign
+	if tx.spentOutput(inID, prevTXs).Type == OutputSchnorr {
+		sign = ecc.SchnorrSign
+	}
+	signature, err := sign(privKey, hash)
+	if err != nil {
+		return err
+	}
+	tx.Vin[inID].Signature = append(signature, byte(hashType))
+
+	return nil
+}
+
+// Return the output spent by input inID, which checkPrevTransactions found
+// in prevTXs
+func (tx *Transaction) spentOutput(inID int, prevTXs map[string]Transaction) TXOutput {
+	vin := tx.Vin[inID]
+	return prevTXs[hex.EncodeToString(vin.Txid)].Vout[vin.Vout]
+}
+
+// Verify the signature of input inID, which ends with its hash type.
+// Schnorr signatures are queued on verifier instead, if it is not nil.
+func (tx *Transaction) verifyInput(inID int, prevTXs map[string]Transaction, verifier *ecc.SchnorrVerifier) error {
+	vin := tx.Vin[inID]
+	if len(vin.Signature) == 0 {
+		return fmt.Errorf("input %d is not signed", inID)
+	}
+	spent := tx.spentOutput(inID, prevTXs)
+	hashType := SigHashType(vin.Signature[len(vin.Signature)-1])
+	signature := vin.Signature[:len(vin.Signature)-1]
+
+	hash, err := tx.SignatureHash(inID, hashType, prevTXs)
+	if err != nil {
+		return err
+	}
+
+	if spent.Type != OutputSchnorr {
+		return ecc.Verify(vin.PubKey, hash, signature)
+	}
+	if verifier == nil {
+		return ecc.SchnorrVerify(vin.PubKey, hash, signature)
+	}
+	if _, err := ecc.ParseSchnorrPubKey(vin.PubKey); err != nil {
+		return err
+	}
+	if len(signature) != ecc.SchnorrSignatureLen {
+		return fmt.Errorf("%w: %d bytes", ecc.ErrInvalidSignature, len(signature))
+	}
+	verifier.Add(vin.PubKey, hash, signature)
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package tx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// Return a transaction of two inputs and two outputs and the transaction
+// whose outputs it spends
+func sigHashTransaction() (*Transaction, map[string]Transaction) {
+	prev := Transaction{
+		Version: CurrentVersion,
+		ID:      bytes.Repeat([]byte{1}, 32),
+		Vout: []TXOutput{
+			{Value: 5 * Coin, PubKeyHash: make([]byte, 20), Type: OutputPubKeyHash},
+			{Value: 7 * Coin, PubKeyHash: make([]byte, 20), Type: OutputPubKeyHash},
+		},
+	}
+	spend := &Transaction{
+		Version: CurrentVersion,
+		Vin:     []TXInput{{Txid: prev.ID, Vout: 0}, {Txid: prev.ID, Vout: 1}},
+		Vout: []TXOutput{
+			{Value: 4 * Coin, PubKeyHash: bytes.Repeat([]byte{2}, 20), Type: OutputPubKeyHash},
+			{Value: 6 * Coin, PubKeyHash: bytes.Repeat([]byte{3}, 20), Type: OutputSchnorr},
+		},
+	}
+
+	return spend, map[string]Transaction{hex.EncodeToString(prev.ID): prev}
+}
+
+// Each hash type commits to the parts of the transaction it names and to
+// nothing else
+func TestSignatureHash(t *testing.T) {
+	changes := []struct {
+		name   string
+		change func(*Transaction, map[string]Transaction)
+	}{
+		{"spent value", func(spend *Transaction, prevTXs map[string]Transaction) {
+			for id, prev := range prevTXs {
+				prev.Vout = append([]TXOutput{{Value: Coin, PubKeyHash: prev.Vout[0].PubKeyHash}}, prev.Vout[1:]...)
+				prevTXs[id] = prev
+			}
+		}},
+		{"other input", func(spend *Transaction, _ map[string]Transaction) { spend.Vin[1].Vout = 2 }},
+		{"added input", func(spend *Transaction, _ map[string]Transaction) {
+			spend.Vin = append(spend.Vin, TXInput{Txid: make([]byte, 32)})
+		}},
+		{"matching output", func(spend *Transaction, _ map[string]Transaction) { spend.Vout[0].Value++ }},
+		{"other output", func(spend *Transaction, _ map[string]Transaction) { spend.Vout[1].Type = OutputPubKeyHash }},
+		{"added output", func(spend *Transaction, _ map[string]Transaction) {
+			spend.Vout = append(spend.Vout, TXOutput{Value: Coin, PubKeyHash: make([]byte, 20)})
+		}},
+	}
+
+	// the changes committed to by each type, by name
+	tests := []struct {
+		hashType  SigHashType
+		committed map[string]bool
+	}{
+		{SigHashAll, map[string]bool{"spent value": true, "other input": true, "added input": true, "matching output": true, "other output": true, "added output": true}},
+		{SigHashNone, map[string]bool{"spent value": true, "other input": true, "added input": true}},
+		{SigHashSingle, map[string]bool{"spent value": true, "other input": true, "added input": true, "matching output": true}},
+		{SigHashAll | SigHashAnyoneCanPay, map[string]bool{"spent value": true, "matching output": true, "other output": true, "added output": true}},
+		{SigHashNone | SigHashAnyoneCanPay, map[string]bool{"spent value": true}},
+		{SigHashSingle | SigHashAnyoneCanPay, map[string]bool{"spent value": true, "matching output": true}},
+	}
+
+	hashes := make(map[string]SigHashType)
+	for _, tt := range tests {
+		spend, prevTXs := sigHashTransaction()
+		hash, err := spend.SignatureHash(0, tt.hashType, prevTXs)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.hashType, err)
+		}
+		if other, ok := hashes[string(hash)]; ok {
+			t.Errorf("%s and %s have the same hash", tt.hashType, other)
+		}
+		hashes[string(hash)] = tt.hashType
+
+		for _, c := range changes {
+			changed, prevTXs := sigHashTransaction()
+			c.change(changed, prevTXs)
+			got, err := changed.SignatureHash(0, tt.hashType, prevTXs)
+			if err != nil {
+				t.Fatalf("%s, %s: %v", tt.hashType, c.name, err)
+			}
+			if committed := !bytes.Equal(got, hash); committed != tt.committed[c.name] {
+				t.Errorf("%s: %s committed: %t, want %t", tt.hashType, c.name, committed, tt.committed[c.name])
+			}
+		}
+	}
+}
+
+func TestSignatureHashErrors(t *testing.T) {
+	for _, hashType := range []SigHashType{0x00, 0x04, SigHashAnyoneCanPay, 0x41, 0xff} {
+		spend, prevTXs := sigHashTransaction()
+		if _, err := spend.SignatureHash(0, hashType, prevTXs); !errors.Is(err, ErrInvalidTransaction) {
+			t.Errorf("type %s: got %v, want %v", hashType, err, ErrInvalidTransaction)
+		}
+	}
+
+	// SINGLE needs an output with the signed input's index
+	spend, prevTXs := sigHashTransaction()
+	spend.Vout = spend.Vout[:1]
+	if _, err := spend.SignatureHash(0, SigHashSingle, prevTXs); err != nil {
+		t.Fatal(err)
+	}
+	for _, hashType := range []SigHashType{SigHashSingle, SigHashSingle | SigHashAnyoneCanPay} {
+		if _, err := spend.SignatureHash(1, hashType, prevTXs); !errors.Is(err, ErrInvalidTransaction) {
+			t.Errorf("type %s without a matching output: got %v, want %v", hashType, err, ErrInvalidTransaction)
+		}
+	}
+
+	if _, err := spend.SignatureHash(2, SigHashAll, prevTXs); !errors.Is(err, ErrInvalidTransaction) {
+		t.Errorf("missing input: got %v, want %v", err, ErrInvalidTransaction)
+	}
+	delete(prevTXs, hex.EncodeToString(spend.Vin[0].Txid))
+	if _, err := spend.SignatureHash(0, SigHashAll, prevTXs); !errors.Is(err, ErrTxNotFound) {
+		t.Errorf("missing previous transaction: got %v, want %v", err, ErrTxNotFound)
+	}
+}
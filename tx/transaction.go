@@ -0,0 +1,435 @@
+// Package tx defines transactions, amounts and coin selection
+package tx
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/jplesperance/tcn/encoding/canonical"
+	"github.com/jplesperance/tcn/encoding/ecc"
+	"github.com/jplesperance/tcn/wallet"
+)
+
+// Subsidy is the value a coinbase may create on top of the fees of its block
+const Subsidy = 10 * Coin
+
+// Transaction format versions.  Version 0 marks transactions migrated from
+// the legacy gob encoding, whose IDs cannot be re-derived and whose
+// signatures are trusted as stored: their output values are encoded in whole
+// coins rather than sub-units and their outputs have no OutputType.
+// Versions 1 to 4 were intermediate formats and are no longer accepted.
+const (
+	LegacyVersion  = 0
+	CurrentVersion = 5
+)
+
+// Transaction transfers value from the outputs it spends to new outputs
+type Transaction struct {
+	Version int
+	ID      []byte
+	Vin     []TXInput
+	Vout    []TXOutput
+}
+
+// IsCoinbase reports whether the transaction mints the block reward
+func (tx Transaction) IsCoinbase() bool {
+	return len(tx.Vin) == 1 && len(tx.Vin[0].Txid) == 0 && tx.Vin[0].Vout == -1
+}
+
+// Hash returns the hash of a transaction, which commits to its signatures
+// and is what the merkle root of a block is computed over
+func (tx *Transaction) Hash() []byte {
+	var hash [32]byte
+	txCopy := *tx
+	txCopy.ID = []byte{}
+
+	hash = sha256.Sum256(txCopy.Serialize())
+	return hash[:]
+}
+
+// ComputeID returns the ID of a transaction: its hash with every signature
+// empty, so that signing does not change it
+func (tx *Transaction) ComputeID() []byte {
+	txCopy := *tx
+	txCopy.Vin = make([]TXInput, len(tx.Vin))
+	for i, vin := range tx.Vin {
+		vin.Signature = nil
+		txCopy.Vin[i] = vin
+	}
+
+	return txCopy.Hash()
+}
+
+// Sign signs each input of the transaction spending an output owned by privKey.
+// Inputs belonging to other keys are left untouched so a transaction
+// drawing from several wallets can be signed one key at a time.  Nonces
+// are derived from the key and the signed hash, so signing is repeatable.
+// The signatures commit to the whole transaction, see SignWithHashType.
+func (tx *Transaction) Sign(privKey ecdsa.PrivateKey, prevTXs map[string]Transaction) error {
+	return tx.SignWithHashType(privKey, prevTXs, SigHashAll)
+}
+
+// SignWithHashType signs like Sign, committing to the inputs and outputs
+// selected by hashType.  Only transactions of CurrentVersion can be signed.
+func (tx *Transaction) SignWithHashType(privKey ecdsa.PrivateKey, prevTXs map[string]Transaction, hashType SigHashType) error {
+	if tx.IsCoinbase() {
+		return nil
+	}
+	if tx.Version != CurrentVersion {
+		return fmt.Errorf("%w: version %d transactions cannot be signed", ErrInvalidTransaction, tx.Version)
+	}
+	if !hashType.Valid() {
+		return fmt.Errorf("%w: unknown signature hash type %s", ErrInvalidTransaction, hashType)
+	}
+
+	if err := checkPrevTransactions(tx, prevTXs); err != nil {
+		return err
+	}
+
+	for inID, vin := range tx.Vin {
+		if !usesPrivateKey(vin.PubKey, &privKey) {
+			continue
+		}
+		if err := tx.signInput(inID, &privKey, hashType, prevTXs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Report whether pubKey, in any of its encodings, is the public key of
+// privKey
+func usesPrivateKey(pubKey []byte, privKey *ecdsa.PrivateKey) bool {
+	if len(pubKey) == ecc.SchnorrPubKeyLen {
+		return bytes.Equal(pubKey, ecc.SchnorrPubKey(privKey))
+	}
+	pub, err := ecc.ParsePubKey(pubKey)
+
+	return err == nil && pub.X.Cmp(privKey.PublicKey.X) == 0 && pub.Y.Cmp(privKey.PublicKey.Y) == 0
+}
+
+// Check that prevTXs holds every output spent by tx
+func checkPrevTransactions(tx *Transaction, prevTXs map[string]Transaction) error {
+	for _, vin := range tx.Vin {
+		prevTx := prevTXs[hex.EncodeToString(vin.Txid)]
+		if prevTx.ID == nil {
+			return fmt.Errorf("%w: previous transaction %x", ErrTxNotFound, vin.Txid)
+		}
+		if vin.Vout < 0 || vin.Vout >= len(prevTx.Vout) {
+			return fmt.Errorf("%w: output %x:%d does not exist", ErrInvalidTransaction, vin.Txid, vin.Vout)
+		}
+	}
+
+	return nil
+}
+
+func (tx Transaction) String() string {
+	var lines []string
+	lines = append(lines, fmt.Sprintf("--- Transaction %x:", tx.ID))
+	for i, input := range tx.Vin {
+		lines = append(lines, fmt.Sprintf("      Input %d:", i))
+		lines = append(lines, fmt.Sprintf("        TXID:      %x", input.Txid))
+		lines = append(lines, fmt.Sprintf("        Out:       %d", input.Vout))
+		lines = append(lines, fmt.Sprintf("        Signature: %x", input.Signature))
+		lines = append(lines, fmt.Sprintf("        PubKey:    %x", input.PubKey))
+	}
+
+	for i, output := range tx.Vout {
+		lines = append(lines, fmt.Sprintf("      Output %d", i))
+		lines = append(lines, fmt.Sprintf("        Value: %s", output.Value))
+		lines = append(lines, fmt.Sprintf("        Script: %x", output.PubKeyHash))
+		lines = append(lines, fmt.Sprintf("        Type: %s", output.Type))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Verify checks that the public key of every input hashes to the public key
+// hash of the output it spends and that its signature verifies, returning
+// ErrInvalidSignature if any does not
+func (tx *Transaction) Verify(prevTXs map[string]Transaction) error {
+	return tx.VerifyWith(prevTXs, nil)
+}
+
+// VerifyWith checks the signatures like Verify, except that Schnorr
+// signatures are queued on verifier, if not nil, instead of being checked.
+// The transaction is only valid once the verifier succeeds too.  Only
+// transactions of CurrentVersion can be verified.
+func (tx *Transaction) VerifyWith(prevTXs map[string]Transaction, verifier *ecc.SchnorrVerifier) error {
+	if tx.IsCoinbase() {
+		return nil
+	}
+	if tx.Version != CurrentVersion {
+		return fmt.Errorf("%w: version %d transactions cannot be verified", ErrInvalidTransaction, tx.Version)
+	}
+
+	if err := checkPrevTransactions(tx, prevTXs); err != nil {
+		return err
+	}
+
+	for inID, vin := range tx.Vin {
+		spent := prevTXs[hex.EncodeToString(vin.Txid)].Vout[vin.Vout]
+		// the signature only proves the key, which must be the one the
+		// output is locked to
+		if !vin.UsesKey(spent.PubKeyHash) {
+			return &InvalidSignatureError{tx.ID}
+		}
+		if err := tx.verifyInput(inID, prevTXs, verifier); err != nil {
+			return &InvalidSignatureError{tx.ID}
+		}
+	}
+
+	return nil
+}
+
+// Serialize encodes the transaction into its canonical binary encoding
+func (tx Transaction) Serialize() []byte {
+	var e canonical.Encoder
+	tx.encode(&e)
+
+	return e.Bytes()
+}
+
+// DeserializeTransaction decodes a transaction from its canonical binary encoding
+func DeserializeTransaction(data []byte) (Transaction, error) {
+	var tx Transaction
+	d := canonical.NewDecoder(data)
+	tx.decode(d)
+	if err := d.Finish(); err != nil {
+		return Transaction{}, fmt.Errorf("failed to decode transaction: %v", err)
+	}
+
+	return tx, nil
+}
+
+func (tx *Transaction) encode(e *canonical.Encoder) {
+	e.WriteUvarint(uint64(tx.Version))
+	e.WriteBytes(tx.ID)
+
+	e.WriteUvarint(uint64(len(tx.Vin)))
+	for _, in := range tx.Vin {
+		e.WriteBytes(in.Txid)
+		e.WriteVarint(int64(in.Vout))
+		e.WriteBytes(in.Signature)
+		e.WriteBytes(in.PubKey)
+	}
+
+	e.WriteUvarint(uint64(len(tx.Vout)))
+	for _, out := range tx.Vout {
+		e.WriteVarint(int64(out.Value / tx.valueUnit()))
+		e.WriteBytes(out.PubKeyHash)
+		if tx.Version != LegacyVersion {
+			e.WriteUvarint(uint64(out.Type))
+		}
+	}
+}
+
+func (tx *Transaction) decode(d *canonical.Decoder) {
+	// compared before converting, so that a huge version cannot wrap
+	version := d.ReadUvarint()
+	if d.Err() == nil && version != LegacyVersion && version != CurrentVersion {
+		d.Fail(fmt.Errorf("unsupported transaction version %d", version))
+		return
+	}
+	tx.Version = int(version)
+	tx.ID = d.ReadBytes()
+
+	tx.Vin = make([]TXInput, d.ReadCount())
+	for i := range tx.Vin {
+		tx.Vin[i].Txid = d.ReadBytes()
+		tx.Vin[i].Vout = int(d.ReadVarint())
+		tx.Vin[i].Signature = d.ReadBytes()
+		tx.Vin[i].PubKey = d.ReadBytes()
+	}
+
+	tx.Vout = make([]TXOutput, d.ReadCount())
+	for i := range tx.Vout {
+		value := d.ReadVarint()
+		if value > int64(MaxMoney/tx.valueUnit()) || value < -int64(MaxMoney/tx.valueUnit()) {
+			d.Fail(ErrAmountOverflow)
+			return
+		}
+		tx.Vout[i].Value = Amount(value) * tx.valueUnit()
+		tx.Vout[i].PubKeyHash = d.ReadBytes()
+		if tx.Version != LegacyVersion {
+			tx.Vout[i].Type = OutputType(d.ReadUvarint())
+			if d.Err() == nil && !tx.Vout[i].Type.Valid() {
+				d.Fail(fmt.Errorf("unknown output type %d", tx.Vout[i].Type))
+				return
+			}
+		}
+	}
+}
+
+// Return the unit output values are encoded in for this transaction version
+func (tx *Transaction) valueUnit() Amount {
+	if tx.Version == LegacyVersion {
+		return Coin
+	}
+
+	return 1
+}
+
+// CheckSanity performs the checks that need no chain context: the ID
+// matches the transaction, it has inputs and outputs, every output value
+// lies within 0 and MaxMoney and so does their total.  The IDs of legacy
+// transactions cannot be recomputed and are not checked.
+func (tx *Transaction) CheckSanity() error {
+	if tx.Version != LegacyVersion && !bytes.Equal(tx.ID, tx.ComputeID()) {
+		return fmt.Errorf("transaction %x does not match its ID, expected %x", tx.ID, tx.ComputeID())
+	}
+	if len(tx.Vin) == 0 {
+		return fmt.Errorf("transaction %x has no inputs", tx.ID)
+	}
+	if len(tx.Vout) == 0 {
+		return fmt.Errorf("transaction %x has no outputs", tx.ID)
+	}
+
+	if _, err := tx.OutputValue(); err != nil {
+		return fmt.Errorf("transaction %x: %v", tx.ID, err)
+	}
+
+	return nil
+}
+
+// OutputValue returns the checked total of the transaction's outputs
+func (tx *Transaction) OutputValue() (Amount, error) {
+	var total Amount
+	for _, out := range tx.Vout {
+		var err error
+		total, err = total.Add(out.Value)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return total, nil
+}
+
+// TXInput spends an output of an earlier transaction
+type TXInput struct {
+	Txid      []byte
+	Vout      int
+	Signature []byte
+	PubKey    []byte
+}
+
+// TXOutput locks a value to a public key hash
+type TXOutput struct {
+	Value      Amount
+	PubKeyHash []byte
+	Type       OutputType
+}
+
+// OutputType selects the signature scheme spending an output.  Outputs of
+// legacy transactions are all OutputPubKeyHash.
+type OutputType int
+
+const (
+	// OutputPubKeyHash is spent with an ECDSA signature by a key hashing
+	// to the output's public key hash
+	OutputPubKeyHash OutputType = iota
+	// OutputSchnorr is spent with a Schnorr signature by a key hashing to
+	// the output's public key hash, which may aggregate several keys
+	OutputSchnorr
+)
+
+var outputTypeNames = map[OutputType]string{
+	OutputPubKeyHash: "pubkeyhash",
+	OutputSchnorr:    "schnorr",
+}
+
+// Valid reports whether t is one of the defined types
+func (t OutputType) Valid() bool {
+	_, ok := outputTypeNames[t]
+	return ok
+}
+
+func (t OutputType) String() string {
+	if name, ok := outputTypeNames[t]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("type %d", int(t))
+}
+
+// UsesKey reports whether the input was signed by the key hashing to
+// pubKeyHash
+func (in *TXInput) UsesKey(pubKeyHash []byte) bool {
+	lockingHash := wallet.HashPubKey(in.PubKey)
+	return bytes.Compare(lockingHash, pubKeyHash) == 0
+}
+
+// Lock locks the output to the public key hash encoded in address, as a
+// Schnorr output for the address of a Schnorr key
+func (out *TXOutput) Lock(address []byte) error {
+	pubKeyHash, err := wallet.DecodeAddress(string(address))
+	if err != nil {
+		return err
+	}
+	out.PubKeyHash = pubKeyHash
+	out.Type = OutputPubKeyHash
+	if wallet.IsSchnorrAddress(string(address)) {
+		out.Type = OutputSchnorr
+	}
+
+	return nil
+}
+
+// IsLockedWithKey reports whether the output is locked to pubKeyHash
+func (out TXOutput) IsLockedWithKey(pubKeyHash []byte) bool {
+	return bytes.Compare(out.PubKeyHash, pubKeyHash) == 0
+}
+
+// NewTXOutput creates an output paying value to address
+func NewTXOutput(value Amount, address string) (*TXOutput, error) {
+	txo := &TXOutput{value, nil, OutputPubKeyHash}
+	if err := txo.Lock([]byte(address)); err != nil {
+		return nil, err
+	}
+
+	return txo, nil
+}
+
+// NewCoinbaseTX creates the transaction paying the block reward, the
+// subsidy and the fees of the block, to to
+func NewCoinbaseTX(to, data string, fees Amount) (*Transaction, error) {
+	if data == "" {
+		data = fmt.Sprintf("Reward to '%s'", to)
+	}
+	reward, err := Subsidy.Add(fees)
+	if err != nil {
+		return nil, err
+	}
+
+	txin := TXInput{[]byte{}, -1, nil, []byte(data)}
+	txout, err := NewTXOutput(reward, to)
+	if err != nil {
+		return nil, err
+	}
+	tx := Transaction{CurrentVersion, nil, []TXInput{txin}, []TXOutput{*txout}}
+	tx.ID = tx.ComputeID()
+
+	return &tx, nil
+}
+
+// SigOpCount returns the number of signature checks needed to validate the
+// transaction
+func (tx *Transaction) SigOpCount() int {
+	if tx.IsCoinbase() {
+		return 0
+	}
+
+	return len(tx.Vin)
+}
+
+// SerializedSize returns the size of the serialized transaction as it is
+// stored in a block
+func (tx *Transaction) SerializedSize() int {
+	return len(tx.Serialize())
+}
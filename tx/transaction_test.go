@@ -0,0 +1,106 @@
+package tx
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/jplesperance/tcn/encoding/ecc"
+	"github.com/jplesperance/tcn/wallet"
+)
+
+// Return the key with the given hex encoded scalar
+func fixedKey(t *testing.T, d string) ecdsa.PrivateKey {
+	t.Helper()
+
+	scalar, ok := new(big.Int).SetString(d, 16)
+	if !ok {
+		t.Fatalf("invalid hex scalar %q", d)
+	}
+	curve := elliptic.P256()
+	x, y := curve.ScalarBaseMult(scalar.FillBytes(make([]byte, 32)))
+
+	return ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: curve, X: x, Y: y}, D: scalar}
+}
+
+// Signatures are derived from the key and the signature hash alone, so a
+// fixed transaction signed with a fixed key always gives the same bytes
+func TestSignFixedKey(t *testing.T) {
+	privKey := fixedKey(t, "C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721")
+	pubKey := ecc.CompressPubKey(&privKey.PublicKey)
+	pubKeyHash := wallet.HashPubKey(pubKey)
+
+	prev := Transaction{
+		Version: CurrentVersion,
+		Vin:     []TXInput{{Txid: []byte{}, Vout: -1, PubKey: []byte("fixed")}},
+		Vout:    []TXOutput{{Value: 10 * Coin, PubKeyHash: pubKeyHash, Type: OutputPubKeyHash}},
+	}
+	prev.ID = prev.ComputeID()
+
+	spend := Transaction{
+		Version: CurrentVersion,
+		Vin:     []TXInput{{Txid: prev.ID, Vout: 0, PubKey: pubKey}},
+		Vout: []TXOutput{
+			{Value: 3 * Coin, PubKeyHash: make([]byte, 20), Type: OutputPubKeyHash},
+			{Value: 6 * Coin, PubKeyHash: pubKeyHash, Type: OutputPubKeyHash},
+		},
+	}
+	spend.ID = spend.ComputeID()
+	prevTXs := map[string]Transaction{hex.EncodeToString(prev.ID): prev}
+
+	if err := spend.Sign(privKey, prevTXs); err != nil {
+		t.Fatal(err)
+	}
+
+	const want = "3045022100c95b4a66b78dd4cd869b1a577125049b4fba68c4549a324bdf3379f55876210f0220607746492544c2c8edd280309518433623e1349b102045e4e1662387253ddc2201"
+	if got := hex.EncodeToString(spend.Vin[0].Signature); got != want {
+		t.Fatalf("signature %s, want %s", got, want)
+	}
+	if err := spend.Verify(prevTXs); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Addresses too short to hold a version and checksum used to panic Lock
+func TestNewTXOutputInvalidAddress(t *testing.T) {
+	for _, address := range []string{"", "1", "11111", "not an address"} {
+		if _, err := NewTXOutput(Coin, address); !errors.Is(err, wallet.ErrInvalidAddress) {
+			t.Errorf("address %q: got %v, want %v", address, err, wallet.ErrInvalidAddress)
+		}
+	}
+}
+
+// Only legacy and current transactions decode, and only current ones can
+// be signed
+func TestTransactionVersions(t *testing.T) {
+	privKey := fixedKey(t, "C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721")
+	pubKey := ecc.CompressPubKey(&privKey.PublicKey)
+
+	for version := LegacyVersion; version <= CurrentVersion+1; version++ {
+		spend := Transaction{
+			Version: version,
+			Vin:     []TXInput{{Txid: make([]byte, 32), Vout: 0, PubKey: pubKey}},
+			Vout:    []TXOutput{{Value: Coin, PubKeyHash: make([]byte, 20)}},
+		}
+		spend.ID = spend.ComputeID()
+
+		_, err := DeserializeTransaction(spend.Serialize())
+		supported := version == LegacyVersion || version == CurrentVersion
+		if supported != (err == nil) {
+			t.Errorf("version %d: decoding returned %v", version, err)
+		}
+
+		prev := Transaction{ID: spend.Vin[0].Txid, Vout: []TXOutput{{Value: Coin, PubKeyHash: wallet.HashPubKey(pubKey)}}}
+		prevTXs := map[string]Transaction{hex.EncodeToString(prev.ID): prev}
+		err = spend.Sign(privKey, prevTXs)
+		if version == CurrentVersion && err != nil {
+			t.Errorf("version %d: %v", version, err)
+		}
+		if version != CurrentVersion && !errors.Is(err, ErrInvalidTransaction) {
+			t.Errorf("version %d: signing returned %v, want %v", version, err, ErrInvalidTransaction)
+		}
+	}
+}
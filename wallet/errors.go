@@ -0,0 +1,16 @@
+package wallet
+
+import "errors"
+
+// Errors returned by the wallet API.  Callers can test for them with
+// errors.Is.
+var (
+	ErrInvalidAddress          = errors.New("address is not valid")
+	ErrWalletNotFound          = errors.New("address is not in the wallet file")
+	ErrWalletFileNotFound      = errors.New("wallet file not found")
+	ErrInvalidPrivateKey       = errors.New("invalid private key")
+	ErrInvalidPublicKey        = errors.New("invalid public key")
+	ErrInvalidMessageSignature = errors.New("message signature is not valid")
+	ErrWatchOnly               = errors.New("address is watch-only, its private key is not in the wallet file")
+	ErrInvalidVanityPrefix     = errors.New("vanity prefix cannot be found")
+)
@@ -0,0 +1,151 @@
+package wallet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The wallet file is guarded by an advisory lock on a file of its own,
+// named after it with this suffix, held from loading the wallets until they
+// are closed, so that concurrent runs modify it one after the other instead
+// of overwriting each other's keys.  The lock is not taken on the wallet
+// file itself, which saving replaces.
+const walletLockSuffix = ".lock"
+
+// Before the wallet file is replaced, its current content is copied into
+// this directory, next to the wallet file, under its name followed by a
+// timestamp, and only the newest backups are kept
+const (
+	walletBackupDir  = "wallet-backups"
+	walletBackupKeep = 10
+)
+
+// Layout of the timestamp in backup names, which sort in time order
+const walletBackupTime = "20060102T150405.000000000Z"
+
+// Take the lock of the wallet file at path, waiting for another process to
+// release it
+func lockWalletFile(path string) (*os.File, error) {
+	lockFile := path + walletLockSuffix
+	f, err := os.OpenFile(lockFile, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	locked, err := flock(f, false)
+	if err == nil && !locked {
+		log.Info("waiting for wallet file lock", "file", lockFile)
+		_, err = flock(f, true)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Write data to path through a temporary file renamed over it, so that path
+// holds either its old or its new content even after a crash
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	f, err := ioutil.TempFile(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+
+	// make the rename itself durable, where directories can be synced
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
+
+// Return the backup directory of the wallet file at path and the name of
+// its backups before and after the timestamp
+func walletBackupName(path string) (dir, prefix, ext string) {
+	dir = filepath.Join(filepath.Dir(path), walletBackupDir)
+	ext = filepath.Ext(path)
+	prefix = strings.TrimSuffix(filepath.Base(path), ext) + "-"
+
+	return dir, prefix, ext
+}
+
+// Copy the wallet file at file into the backup directory, returning the
+// backup's path, empty if there is no wallet file yet
+func backupWalletFile(file string) (string, error) {
+	content, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	dir, prefix, ext := walletBackupName(file)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, prefix+time.Now().UTC().Format(walletBackupTime)+ext)
+	if err := writeFileAtomic(path, content); err != nil {
+		return "", err
+	}
+	log.Debug("backed up wallet file", "file", file, "backup", path)
+
+	pruneWalletBackups(file)
+
+	return path, nil
+}
+
+// Remove all but the newest automatic backups of the wallet file at file.
+// Failures only leave more backups behind, so they are logged and otherwise
+// ignored.
+func pruneWalletBackups(file string) {
+	dir, prefix, ext := walletBackupName(file)
+	matches, err := filepath.Glob(filepath.Join(dir, prefix+"*"+ext))
+	if err != nil {
+		return
+	}
+
+	// the pattern also matches the backups of wallet files whose name
+	// starts with this one's
+	var backups []string
+	for _, path := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), prefix), ext)
+		if _, err := time.Parse(walletBackupTime, stamp); err == nil {
+			backups = append(backups, path)
+		}
+	}
+	if len(backups) <= walletBackupKeep {
+		return
+	}
+	sort.Strings(backups)
+
+	for _, path := range backups[:len(backups)-walletBackupKeep] {
+		if err := os.Remove(path); err != nil {
+			log.Warn("cannot remove old wallet backup", "backup", path, "err", err)
+		}
+	}
+}
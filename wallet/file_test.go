@@ -0,0 +1,145 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// Open the wallets of file, which may not exist yet
+func openWallets(t *testing.T, file string) *Wallets {
+	t.Helper()
+
+	ws, err := NewWallets(Options{File: file})
+	if err != nil && !errors.Is(err, ErrWalletFileNotFound) {
+		t.Fatal(err)
+	}
+
+	return ws
+}
+
+// A second run waits for the first to close its wallets, and then sees the
+// keys the first one saved
+func TestWalletFileLock(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "wallet.dat")
+	first := openWallets(t, file)
+	address, err := first.CreateWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := os.Open(file + walletLockSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Close()
+	if locked, err := flock(lock, false); err != nil || locked {
+		t.Fatalf("lock taken while held: %t, %v", locked, err)
+	}
+
+	opened := make(chan *Wallets)
+	go func() {
+		ws, err := NewWallets(Options{File: file})
+		if err != nil {
+			t.Error(err)
+		}
+		opened <- ws
+	}()
+
+	select {
+	case <-opened:
+		t.Fatal("the wallets were opened while locked")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if err := first.SaveToFile(); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var second *Wallets
+	select {
+	case second = <-opened:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the wallets were not opened once the lock was released")
+	}
+	defer second.Close()
+	if _, err := second.GetWallet(address); err != nil {
+		t.Fatalf("the saved wallet is missing: %v", err)
+	}
+}
+
+// Return the names of the backups in dir, sorted
+func backupNames(t *testing.T, dir string) []string {
+	t.Helper()
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func TestWalletBackupRotation(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "wallet.dat")
+	dir := filepath.Join(filepath.Dir(file), walletBackupDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	// an older backup, and one of a wallet file whose name starts with this
+	// one's, which is not a backup of this file
+	oldest := "wallet-" + time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC).Format(walletBackupTime) + ".dat"
+	other := "wallet-other-" + time.Now().UTC().Format(walletBackupTime) + ".dat"
+	for _, name := range []string{oldest, other} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ws := openWallets(t, file)
+	defer ws.Close()
+	var saved [][]byte
+	for i := 0; i < walletBackupKeep+2; i++ {
+		if _, err := ws.CreateWallet(); err != nil {
+			t.Fatal(err)
+		}
+		if err := ws.SaveToFile(); err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved = append(saved, content)
+	}
+
+	names := backupNames(t, dir)
+	if len(names) != walletBackupKeep+1 || names[len(names)-1] != other {
+		t.Fatalf("backups %v, want %d of wallet.dat and %s", names, walletBackupKeep, other)
+	}
+
+	// the first save had no file to back up, so the backups hold the
+	// content of every save but the last, of which the newest are kept
+	for i, name := range names[:walletBackupKeep] {
+		content, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := saved[len(saved)-1-walletBackupKeep+i]; !bytes.Equal(content, want) {
+			t.Fatalf("backup %s holds the wrong content", name)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+//go:build !windows && !solaris
+// +build !windows,!solaris
+
+package wallet
+
+import (
+	"os"
+	"syscall"
+)
+
+// Take an exclusive advisory lock on f, reporting false if another process
+// holds it and wait is not set.  The lock is released when f is closed,
+// also when the process dies.
+func flock(f *os.File, wait bool) (bool, error) {
+	how := syscall.LOCK_EX
+	if !wait {
+		how |= syscall.LOCK_NB
+	}
+
+	for {
+		err := syscall.Flock(int(f.Fd()), how)
+		switch err {
+		case nil:
+			return true, nil
+		case syscall.EINTR:
+			continue
+		case syscall.EWOULDBLOCK:
+			return false, nil
+		}
+		return false, err
+	}
+}
@@ -0,0 +1,34 @@
+package wallet
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Take an exclusive advisory lock on f, reporting false if another process
+// holds it and wait is not set.  Solaris has no flock, so this is a POSIX
+// record lock over the whole file: it is held by the process rather than
+// by f, and released when the process closes any descriptor of the file or
+// dies.
+func flock(f *os.File, wait bool) (bool, error) {
+	cmd := unix.F_SETLK
+	if wait {
+		cmd = unix.F_SETLKW
+	}
+	lock := unix.Flock_t{Type: unix.F_WRLCK, Whence: io.SeekStart}
+
+	for {
+		err := unix.FcntlFlock(f.Fd(), cmd, &lock)
+		switch err {
+		case nil:
+			return true, nil
+		case unix.EINTR:
+			continue
+		case unix.EAGAIN, unix.EACCES:
+			return false, nil
+		}
+		return false, err
+	}
+}
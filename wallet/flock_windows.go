@@ -0,0 +1,41 @@
+package wallet
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	modkernel32    = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx = modkernel32.NewProc("LockFileEx")
+)
+
+// Flags of LockFileEx and the error it fails with on a lock held elsewhere
+const (
+	lockfileExclusiveLock   = 2
+	lockfileFailImmediately = 1
+
+	errLockViolation syscall.Errno = 0x21
+)
+
+// Take an exclusive lock on f, reporting false if another process holds it
+// and wait is not set.  The lock is released when f is closed, also when
+// the process dies.
+func flock(f *os.File, wait bool) (bool, error) {
+	flags := uint32(lockfileExclusiveLock)
+	if !wait {
+		flags |= lockfileFailImmediately
+	}
+
+	ol := &syscall.Overlapped{}
+	r, _, err := procLockFileEx.Call(f.Fd(), uintptr(flags), 0, 1, 0, uintptr(unsafe.Pointer(ol)))
+	if r != 0 {
+		return true, nil
+	}
+	if err == errLockViolation {
+		return false, nil
+	}
+
+	return false, err
+}
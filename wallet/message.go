@@ -0,0 +1,83 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/jplesperance/tcn/encoding/canonical"
+	"github.com/jplesperance/tcn/encoding/ecc"
+)
+
+// Message signatures prove ownership of an address without spending from
+// it.  The signed hash is SHA-256(SHA-256(...)) of the canonical encoding of
+// messagePrefix and the message.  Transaction signature hashes are the same
+// double SHA-256 over an encoding starting with their own tag, and both tags
+// are length prefixed, so the two preimages always differ from the first
+// bytes and a message signature cannot be replayed as a transaction
+// signature without a SHA-256 collision.  A signature is the Base64 encoding
+// of the signer's public key and the DER encoded ECDSA signature, or the
+// Schnorr signature for Schnorr keys, both as canonical bytes, so it can be
+// checked against the hash in the address.
+const messagePrefix = "TCN Signed Message:\n"
+
+func messageHash(message string) []byte {
+	var e canonical.Encoder
+	e.WriteBytes([]byte(messagePrefix))
+	e.WriteBytes([]byte(message))
+
+	first := sha256.Sum256(e.Bytes())
+	second := sha256.Sum256(first[:])
+
+	return second[:]
+}
+
+// SignMessage signs message with the wallet's private key
+func (w Wallet) SignMessage(message string) (string, error) {
+	sign := ecc.Sign
+	if w.IsSchnorr() {
+		sign = ecc.SchnorrSign
+	}
+	signature, err := sign(&w.PrivateKey, messageHash(message))
+	if err != nil {
+		return "", err
+	}
+
+	var e canonical.Encoder
+	e.WriteBytes(w.PublicKey)
+	e.WriteBytes(signature)
+
+	return base64.StdEncoding.EncodeToString(e.Bytes()), nil
+}
+
+// VerifyMessage checks a signature made by SignMessage with the key of
+// address, returning ErrInvalidMessageSignature if it does not match
+func VerifyMessage(address, signature, message string) error {
+	if !ValidateAddress(address) {
+		return fmt.Errorf("%w: %s", ErrInvalidAddress, address)
+	}
+
+	encoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMessageSignature, err)
+	}
+	d := canonical.NewDecoder(encoded)
+	pubKey := d.ReadBytes()
+	sig := d.ReadBytes()
+	if err := d.Finish(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMessageSignature, err)
+	}
+	if string(PubKeyAddress(pubKey)) != address {
+		return fmt.Errorf("%w: signed by another address", ErrInvalidMessageSignature)
+	}
+
+	verify := ecc.Verify
+	if pubKeyKind(pubKey) == schnorrKey {
+		verify = ecc.SchnorrVerify
+	}
+	if err := verify(pubKey, messageHash(message), sig); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMessageSignature, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package wallet
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+)
+
+// Migrate rewrites the wallet file selected by opts from the legacy gob
+// format into the canonical binary format, reporting whether anything was
+// converted
+func Migrate(opts Options) (bool, error) {
+	opts = opts.withDefaults()
+	fileContent, err := ioutil.ReadFile(opts.File)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if bytes.HasPrefix(fileContent, walletFileMagic) {
+		return false, nil
+	}
+
+	wallets, err := NewWallets(opts)
+	defer wallets.Close()
+	if err != nil {
+		return false, err
+	}
+
+	if err := wallets.SaveToFile(); err != nil {
+		return false, err
+	}
+	log.Info("migrated wallet file", "file", wallets.file, "wallets", len(wallets.Wallets))
+
+	return true, nil
+}
@@ -0,0 +1,69 @@
+package wallet
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jplesperance/tcn/encoding/base58"
+	"github.com/jplesperance/tcn/network"
+)
+
+// Private keys are exported as Base58Check strings, like addresses: the
+// private key prefix of the network, the 32 byte private scalar, a
+// flag byte giving the encoding of the wallet's public key, absent for
+// legacy keys, and a 4 byte checksum of the rest.
+
+const (
+	compressedKeyFlag = 0x01
+	schnorrKeyFlag    = 0x02
+)
+
+// ExportPrivateKey returns the wallet's private key encoded for the network
+// with the given parameters
+func (w Wallet) ExportPrivateKey(params *network.Params) string {
+	payload := make([]byte, 1+privKeyLen)
+	payload[0] = params.PrivateKeyPrefix
+	w.PrivateKey.D.FillBytes(payload[1:])
+	switch pubKeyKind(w.PublicKey) {
+	case compressedKey:
+		payload = append(payload, compressedKeyFlag)
+	case schnorrKey:
+		payload = append(payload, schnorrKeyFlag)
+	}
+
+	return string(base58.Encode(append(payload, checksum(payload)...)))
+}
+
+// ImportPrivateKey rebuilds a wallet from a private key exported with
+// ExportPrivateKey for the network with the given parameters
+func ImportPrivateKey(key string, params *network.Params) (*Wallet, error) {
+	if len(key) == 0 {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	decoded := base58.Decode([]byte(key))
+	if len(decoded) != 1+privKeyLen+AddressChecksumLen && len(decoded) != 1+privKeyLen+1+AddressChecksumLen {
+		return nil, fmt.Errorf("%w: wrong length", ErrInvalidPrivateKey)
+	}
+
+	payload := decoded[:len(decoded)-AddressChecksumLen]
+	if !bytes.Equal(checksum(payload), decoded[len(payload):]) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidPrivateKey)
+	}
+	if payload[0] != params.PrivateKeyPrefix {
+		return nil, fmt.Errorf("%w: the key is not for the %s network", ErrInvalidPrivateKey, params.Name)
+	}
+	kind := legacyKey
+	if len(payload) == 1+privKeyLen+1 {
+		switch payload[1+privKeyLen] {
+		case compressedKeyFlag:
+			kind = compressedKey
+		case schnorrKeyFlag:
+			kind = schnorrKey
+		default:
+			return nil, fmt.Errorf("%w: unknown key flag", ErrInvalidPrivateKey)
+		}
+	}
+
+	return newWalletFromKey(payload[1:1+privKeyLen], kind)
+}
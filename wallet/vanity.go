@@ -0,0 +1,198 @@
+package wallet
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jplesperance/tcn/encoding/base58"
+)
+
+// Vanity addresses start with a chosen Base58 prefix.  An address encodes the
+// version byte followed by 24 bytes, the public key hash and checksum, which
+// are as good as random, so whether a prefix can occur and how often is
+// worked out from the range of numbers the version byte leaves.
+
+// Bits encoded after the version byte
+const addressPayloadBits = 8 * (20 + AddressChecksumLen)
+
+// Interval between progress reports of FindVanityWallet
+const vanityProgressInterval = 5 * time.Second
+
+// Return the range [lo, hi) of the numbers an address of the version
+// encodes after ones leading '1's, or false if no address starts with that
+// many.  Version 0 addresses start with a '1' for the version byte and one
+// more for each leading zero byte of the public key hash.
+func addressRange(addressVersion byte, ones int) (*big.Int, *big.Int, bool) {
+	if addressVersion != 0 {
+		lo := new(big.Int).Lsh(big.NewInt(int64(addressVersion)), addressPayloadBits)
+		hi := new(big.Int).Lsh(big.NewInt(int64(addressVersion)+1), addressPayloadBits)
+		return lo, hi, ones == 0
+	}
+
+	zeros := ones - 1
+	if zeros < 0 || 8*zeros >= addressPayloadBits {
+		return nil, nil, false
+	}
+	lo := new(big.Int).Lsh(big.NewInt(1), uint(addressPayloadBits-8*(zeros+1)))
+	hi := new(big.Int).Lsh(big.NewInt(1), uint(addressPayloadBits-8*zeros))
+	return lo, hi, true
+}
+
+// VanityDifficulty returns the expected number of keys to generate before
+// one's address starts with prefix, for Schnorr keys if schnorr is set.  It
+// returns ErrInvalidVanityPrefix if no address of that kind can start with
+// prefix.
+func VanityDifficulty(prefix string, schnorr bool) (float64, error) {
+	if prefix == "" {
+		return 0, fmt.Errorf("%w: empty prefix", ErrInvalidVanityPrefix)
+	}
+	for _, c := range prefix {
+		if !strings.ContainsRune(base58.Alphabet, c) {
+			return 0, fmt.Errorf("%w: %q is not a Base58 character", ErrInvalidVanityPrefix, c)
+		}
+	}
+
+	addressVersion := version
+	if schnorr {
+		addressVersion = schnorrVersion
+	}
+	ones := len(prefix) - len(strings.TrimLeft(prefix, base58.Alphabet[:1]))
+	lo, hi, ok := addressRange(addressVersion, ones)
+	if !ok {
+		return 0, fmt.Errorf("%w: no address starts with %q", ErrInvalidVanityPrefix, prefix)
+	}
+	rest := prefix[ones:]
+
+	// count the numbers of [lo, hi) whose Base58 digits start with rest,
+	// those of every length.  A prefix of '1's only is matched by every
+	// number below hi, whose addresses start with at least as many.
+	matched := new(big.Int)
+	if rest == "" {
+		matched.Set(hi)
+	} else {
+		p := new(big.Int)
+		for _, c := range []byte(rest) {
+			p.Mul(p, big.NewInt(58))
+			p.Add(p, big.NewInt(int64(strings.IndexByte(base58.Alphabet, c))))
+		}
+
+		scale := big.NewInt(1)
+		for {
+			start := new(big.Int).Mul(p, scale)
+			if start.Cmp(hi) >= 0 {
+				break
+			}
+			end := new(big.Int).Add(start, scale)
+
+			if start.Cmp(lo) < 0 {
+				start = lo
+			}
+			if end.Cmp(hi) > 0 {
+				end = hi
+			}
+			if end.Cmp(start) > 0 {
+				matched.Add(matched, end.Sub(end, start))
+			}
+			scale.Mul(scale, big.NewInt(58))
+		}
+	}
+	if matched.Sign() == 0 {
+		return 0, fmt.Errorf("%w: no address starts with %q", ErrInvalidVanityPrefix, prefix)
+	}
+
+	total := new(big.Int).Lsh(big.NewInt(1), addressPayloadBits)
+	difficulty, _ := new(big.Rat).SetFrac(total, matched).Float64()
+
+	return difficulty, nil
+}
+
+// FindVanityWallet generates keys on workers goroutines until one's address
+// starts with prefix, returning its wallet and the number of keys tried.
+// Progress is logged periodically.
+func FindVanityWallet(prefix string, schnorr bool, workers int) (*Wallet, uint64, error) {
+	difficulty, err := VanityDifficulty(prefix, schnorr)
+	if err != nil {
+		return nil, 0, err
+	}
+	if workers < 1 {
+		return nil, 0, fmt.Errorf("workers must be at least 1, got %d", workers)
+	}
+	log.Info("searching for vanity address", "prefix", prefix, "difficulty", math.Round(difficulty), "workers", workers)
+
+	newWallet := NewWallet
+	if schnorr {
+		newWallet = NewSchnorrWallet
+	}
+
+	var tried uint64
+	found := make(chan *Wallet, 1)
+	failed := make(chan error, 1)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				wallet, err := newWallet()
+				if err != nil {
+					select {
+					case failed <- err:
+					default:
+					}
+					return
+				}
+				atomic.AddUint64(&tried, 1)
+
+				address := string(wallet.GetAddress())
+				if strings.HasPrefix(address, prefix) && ValidateAddress(address) {
+					select {
+					case found <- wallet:
+					default:
+					}
+					return
+				}
+			}
+		}()
+	}
+
+	start := time.Now()
+	ticker := time.NewTicker(vanityProgressInterval)
+	defer ticker.Stop()
+
+	var wallet *Wallet
+	for wallet == nil && err == nil {
+		select {
+		case wallet = <-found:
+		case err = <-failed:
+		case <-ticker.C:
+			n := atomic.LoadUint64(&tried)
+			rate := float64(n) / time.Since(start).Seconds()
+			remaining := time.Duration(math.Max(difficulty-float64(n), 0) / math.Max(rate, 1) * float64(time.Second))
+			log.Info("vanity search progress", "tried", n, "keys_per_second", math.Round(rate),
+				"expected_remaining", remaining.Round(time.Second))
+		}
+	}
+	close(done)
+	wg.Wait()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	n := atomic.LoadUint64(&tried)
+	log.Info("found vanity address", "address", string(wallet.GetAddress()), "tried", n, "elapsed", time.Since(start).Round(time.Millisecond))
+
+	return wallet, n, nil
+}
@@ -0,0 +1,45 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVanityDifficulty(t *testing.T) {
+	tests := []struct {
+		prefix     string
+		schnorr    bool
+		difficulty float64
+	}{
+		{"1", false, 1},
+		{"11", false, 256},
+		{"111", false, 65536},
+		{"S", true, 1},
+		{"2", false, 0},
+		{"1", true, 0},
+		{"10", false, 0},
+	}
+
+	for _, tt := range tests {
+		difficulty, err := VanityDifficulty(tt.prefix, tt.schnorr)
+		if tt.difficulty == 0 {
+			if !errors.Is(err, ErrInvalidVanityPrefix) {
+				t.Errorf("%q: got %v, want %v", tt.prefix, err, ErrInvalidVanityPrefix)
+			}
+			continue
+		}
+		if err != nil || difficulty != tt.difficulty {
+			t.Errorf("%q: got %v, %v, want %v", tt.prefix, difficulty, err, tt.difficulty)
+		}
+	}
+
+	// a prefix of two '1's is found like any other, now that such addresses
+	// decode
+	w, _, err := FindVanityWallet("11", false, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ValidateAddress(string(w.GetAddress())) {
+		t.Fatalf("invalid address %s", w.GetAddress())
+	}
+}
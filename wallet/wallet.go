@@ -0,0 +1,207 @@
+// Package wallet manages key pairs and the addresses derived from them
+package wallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/jplesperance/tcn/encoding/base58"
+	"github.com/jplesperance/tcn/encoding/ecc"
+	"github.com/jplesperance/tcn/logging"
+	"golang.org/x/crypto/ripemd160"
+)
+
+var log = logging.New(logging.Wallet)
+
+// Wallet holds a P-256 key pair.  PublicKey is SEC1 compressed, in the
+// legacy encoding for wallets created before compressed keys, or the 32
+// byte X coordinate for Schnorr wallets; the address is the hash of
+// whichever encoding the wallet holds.
+type Wallet struct {
+	PrivateKey ecdsa.PrivateKey
+	PublicKey  []byte
+}
+
+// Address version bytes.  Addresses of Schnorr keys have a version of their
+// own, so that payments to them create Schnorr outputs.
+const (
+	version        = byte(0x00)
+	schnorrVersion = byte(0x3f)
+)
+
+// Encodings of a wallet's public key
+type keyKind int
+
+const (
+	legacyKey keyKind = iota
+	compressedKey
+	schnorrKey
+)
+
+// Return the kind of a public key from the length of its encoding
+func pubKeyKind(pubKey []byte) keyKind {
+	switch len(pubKey) {
+	case ecc.CompressedPubKeyLen:
+		return compressedKey
+	case ecc.SchnorrPubKeyLen:
+		return schnorrKey
+	}
+
+	return legacyKey
+}
+
+// DefaultWalletFile is the wallet file used when Options names none
+const DefaultWalletFile = "wallet.dat"
+
+// AddressChecksumLen is the number of checksum bytes ending a decoded address
+const AddressChecksumLen = 4
+const privKeyLen = 32
+
+// NewWallet generates a wallet with a fresh key pair
+func NewWallet() (*Wallet, error) {
+	private, public, err := newKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	wallet := Wallet{private, public}
+
+	return &wallet, nil
+}
+
+// NewSchnorrWallet generates a wallet with a fresh key pair for Schnorr
+// signatures
+func NewSchnorrWallet() (*Wallet, error) {
+	private, _, err := newKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	wallet := Wallet{private, ecc.SchnorrPubKey(&private)}
+
+	return &wallet, nil
+}
+
+func newKeyPair() (ecdsa.PrivateKey, []byte, error) {
+	curve := elliptic.P256()
+	private, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return ecdsa.PrivateKey{}, nil, err
+	}
+	pubKey := ecc.CompressPubKey(&private.PublicKey)
+
+	return *private, pubKey, nil
+}
+
+// Rebuild a wallet from the raw P-256 private scalar, with its public key
+// encoded as kind
+func newWalletFromKey(privKey []byte, kind keyKind) (*Wallet, error) {
+	key, err := ecc.NewPrivateKey(privKey)
+	if err != nil {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	private := *key
+	var pubKey []byte
+	switch kind {
+	case compressedKey:
+		pubKey = ecc.CompressPubKey(&private.PublicKey)
+	case schnorrKey:
+		pubKey = ecc.SchnorrPubKey(&private)
+	default:
+		pubKey = ecc.LegacyPubKey(&private.PublicKey)
+	}
+
+	return &Wallet{private, pubKey}, nil
+}
+
+// IsSchnorr reports whether the wallet signs with Schnorr signatures
+func (w Wallet) IsSchnorr() bool {
+	return pubKeyKind(w.PublicKey) == schnorrKey
+}
+
+// GetAddress returns the Base58Check address of the wallet's public key
+func (w Wallet) GetAddress() []byte {
+	return PubKeyAddress(w.PublicKey)
+}
+
+// PubKeyAddress returns the address of a public key, with the version of
+// Schnorr keys for 32 byte keys
+func PubKeyAddress(pubKey []byte) []byte {
+	if pubKeyKind(pubKey) == schnorrKey {
+		return encodeAddress(schnorrVersion, HashPubKey(pubKey))
+	}
+
+	return encodeAddress(version, HashPubKey(pubKey))
+}
+
+// Encode a version byte and public key hash as a Base58Check address
+func encodeAddress(version byte, pubKeyHash []byte) []byte {
+	versionedPayload := append([]byte{version}, pubKeyHash...)
+	checksum := checksum(versionedPayload)
+	fullPayload := append(versionedPayload, checksum...)
+	address := base58.Encode(fullPayload)
+	return address
+}
+
+// HashPubKey returns the RIPEMD160(SHA256) hash of a public key
+func HashPubKey(pubKey []byte) []byte {
+	publicSHA256 := sha256.Sum256(pubKey)
+
+	// writes to a hash.Hash never fail
+	RIPEMD160Hasher := ripemd160.New()
+	RIPEMD160Hasher.Write(publicSHA256[:])
+	publicRIPEMD160 := RIPEMD160Hasher.Sum(nil)
+
+	return publicRIPEMD160
+}
+
+// IsSchnorrAddress reports whether a valid address is that of a Schnorr key
+func IsSchnorrAddress(address string) bool {
+	decoded := base58.Decode([]byte(address))
+	return len(decoded) > 0 && decoded[0] == schnorrVersion
+}
+
+// ValidateAddress checks the version and checksum of a Base58Check address
+func ValidateAddress(address string) bool {
+	_, err := DecodeAddress(address)
+	return err == nil
+}
+
+// DecodeAddress returns the public key hash encoded in a Base58Check
+// address, or ErrInvalidAddress when its version or checksum is wrong
+func DecodeAddress(address string) ([]byte, error) {
+	if len(address) == 0 {
+		return nil, fmt.Errorf("%w: empty address", ErrInvalidAddress)
+	}
+
+	decoded := base58.Decode([]byte(address))
+	if len(decoded) <= 1+AddressChecksumLen {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAddress, address)
+	}
+
+	actualChecksum := decoded[len(decoded)-AddressChecksumLen:]
+
+	addressVersion := decoded[0]
+	if addressVersion != version && addressVersion != schnorrVersion {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAddress, address)
+	}
+
+	pubKeyHash := decoded[1 : len(decoded)-AddressChecksumLen]
+
+	targetChecksum := checksum(append([]byte{addressVersion}, pubKeyHash...))
+	if !bytes.Equal(actualChecksum, targetChecksum) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAddress, address)
+	}
+
+	return pubKeyHash, nil
+}
+
+func checksum(payload []byte) []byte {
+	firstSHA := sha256.Sum256(payload)
+	secondSHA := sha256.Sum256(firstSHA[:])
+
+	return secondSHA[:AddressChecksumLen]
+}
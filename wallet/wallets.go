@@ -0,0 +1,443 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/jplesperance/tcn/encoding/canonical"
+)
+
+// Wallet files start with this magic followed by the format version
+var walletFileMagic = []byte("TCNW")
+
+// Versions 1 to 3 were intermediate formats and are no longer read
+const walletFileVersion = 4
+
+// Options selects the wallet file of Wallets
+//
+// File: path of the wallet file, DefaultWalletFile if empty
+type Options struct {
+	File string
+}
+
+// Fill in the defaults of unset options
+func (o Options) withDefaults() Options {
+	if o.File == "" {
+		o.File = DefaultWalletFile
+	}
+
+	return o
+}
+
+// Wallets stores a collection of wallets and watch-only addresses keyed by
+// address.  Only Wallets hold private keys and can sign.  Labels are kept
+// per address for both.
+type Wallets struct {
+	Wallets   map[string]*Wallet
+	WatchOnly map[string]*WatchOnly
+	Labels    map[string]string
+
+	// file is the path of the wallet file and lock its lock, held until
+	// Close
+	file string
+	lock *os.File
+}
+
+// NewWallets creates Wallets for the wallet file selected by opts and fills
+// it with content from the file if it exists.  It waits for the wallet file
+// lock, which is held until Close, also when the file does not exist yet or
+// cannot be read.
+func NewWallets(opts Options) (*Wallets, error) {
+	opts = opts.withDefaults()
+	wallets := Wallets{file: opts.File}
+	wallets.Wallets = make(map[string]*Wallet)
+	wallets.WatchOnly = make(map[string]*WatchOnly)
+	wallets.Labels = make(map[string]string)
+
+	lock, err := lockWalletFile(wallets.file)
+	if err != nil {
+		return &wallets, err
+	}
+	wallets.lock = lock
+
+	err = wallets.LoadFromFile()
+	return &wallets, err
+}
+
+// Close releases the wallet file lock, letting other processes open the
+// wallet file
+func (ws *Wallets) Close() error {
+	if ws.lock == nil {
+		return nil
+	}
+	err := ws.lock.Close()
+	ws.lock = nil
+
+	return err
+}
+
+// CreateWallet generates a new key pair, adds it to the wallets, returning its address
+func (ws *Wallets) CreateWallet() (string, error) {
+	wallet, err := NewWallet()
+	if err != nil {
+		return "", err
+	}
+
+	return ws.addWallet(wallet), nil
+}
+
+// CreateSchnorrWallet generates a new key pair for Schnorr signatures, adds
+// it to the wallets, returning its address
+func (ws *Wallets) CreateSchnorrWallet() (string, error) {
+	wallet, err := NewSchnorrWallet()
+	if err != nil {
+		return "", err
+	}
+
+	return ws.addWallet(wallet), nil
+}
+
+func (ws *Wallets) addWallet(wallet *Wallet) string {
+	address := fmt.Sprintf("%s", wallet.GetAddress())
+	ws.Wallets[address] = wallet
+	log.Info("created wallet", "address", address)
+
+	return address
+}
+
+// ImportWallet adds a wallet, such as one rebuilt from an imported private
+// key, returning its address and whether it was not already present.  A
+// watch-only entry for the address is replaced.
+func (ws *Wallets) ImportWallet(wallet *Wallet) (string, bool) {
+	address := string(wallet.GetAddress())
+	if _, ok := ws.Wallets[address]; ok {
+		return address, false
+	}
+	delete(ws.WatchOnly, address)
+	ws.Wallets[address] = wallet
+	log.Info("imported wallet", "address", address)
+
+	return address, true
+}
+
+// GetAddresses lists the addresses of every wallet
+func (ws *Wallets) GetAddresses() []string {
+	var addresses []string
+	for address := range ws.Wallets {
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
+// SetLabel sets the label of an address in the wallets, an empty label
+// removing it
+func (ws *Wallets) SetLabel(address, label string) error {
+	if _, ok := ws.Wallets[address]; !ok && !ws.IsWatchOnly(address) {
+		return fmt.Errorf("%w: %s", ErrWalletNotFound, address)
+	}
+
+	if label == "" {
+		delete(ws.Labels, address)
+	} else {
+		ws.Labels[address] = label
+	}
+
+	return nil
+}
+
+// GetLabel returns the label of address, empty if it has none
+func (ws *Wallets) GetLabel(address string) string {
+	return ws.Labels[address]
+}
+
+// GetWallet returns the wallet for address
+func (ws *Wallets) GetWallet(address string) (Wallet, error) {
+	wallet, ok := ws.Wallets[address]
+	if !ok && ws.IsWatchOnly(address) {
+		return Wallet{}, fmt.Errorf("%w: %s", ErrWatchOnly, address)
+	}
+	if !ok {
+		return Wallet{}, fmt.Errorf("%w: %s", ErrWalletNotFound, address)
+	}
+
+	return *wallet, nil
+}
+
+// LoadFromFile loads the wallets from the wallet file, accepting both the canonical
+// binary format and the legacy gob encoding
+func (ws *Wallets) LoadFromFile() error {
+	if _, err := os.Stat(ws.file); os.IsNotExist(err) {
+		return ErrWalletFileNotFound
+	}
+
+	fileContent, err := ioutil.ReadFile(ws.file)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.HasPrefix(fileContent, walletFileMagic) {
+		wallets, err := decodeLegacyWallets(fileContent)
+		if err != nil {
+			return err
+		}
+		ws.Wallets = wallets
+		log.Debug("loaded legacy wallet file", "file", ws.file, "wallets", len(wallets))
+
+		return nil
+	}
+
+	wallets, watchOnly, labels, err := decodeWallets(fileContent[len(walletFileMagic):])
+	if err != nil {
+		return err
+	}
+
+	ws.Wallets = wallets
+	ws.WatchOnly = watchOnly
+	ws.Labels = labels
+	log.Debug("loaded wallet file", "file", ws.file, "wallets", len(wallets), "watch_only", len(watchOnly))
+
+	return nil
+}
+
+// SaveToFile writes the wallets to the wallet file in the canonical binary
+// format, after backing up its current content.  The file is replaced at
+// once, so a crash leaves either the old or the new wallets.  The wallet
+// file lock is taken for the write unless the wallets already hold it.
+func (ws Wallets) SaveToFile() error {
+	if ws.lock == nil {
+		lock, err := lockWalletFile(ws.file)
+		if err != nil {
+			return err
+		}
+		defer lock.Close()
+	}
+
+	var content bytes.Buffer
+	content.Write(walletFileMagic)
+	content.Write(ws.encode())
+
+	if _, err := backupWalletFile(ws.file); err != nil {
+		return fmt.Errorf("cannot back up the wallet file: %w", err)
+	}
+	if err := writeFileAtomic(ws.file, content.Bytes()); err != nil {
+		return err
+	}
+	log.Debug("saved wallet file", "file", ws.file, "wallets", len(ws.Wallets), "watch_only", len(ws.WatchOnly))
+
+	return nil
+}
+
+// Backup copies the wallet file to path, replacing any file there.  It
+// returns ErrWalletFileNotFound if there is no wallet file.
+func (ws *Wallets) Backup(path string) error {
+	if ws.lock == nil {
+		lock, err := lockWalletFile(ws.file)
+		if err != nil {
+			return err
+		}
+		defer lock.Close()
+	}
+
+	content, err := ioutil.ReadFile(ws.file)
+	if os.IsNotExist(err) {
+		return ErrWalletFileNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(path, content); err != nil {
+		return err
+	}
+	log.Info("backed up wallet file", "file", ws.file, "backup", path)
+
+	return nil
+}
+
+// Wallets are written in address order so the encoding is deterministic
+func (ws Wallets) encode() []byte {
+	var e canonical.Encoder
+	addresses := ws.GetAddresses()
+	sort.Strings(addresses)
+
+	e.WriteUvarint(walletFileVersion)
+	e.WriteUvarint(uint64(len(addresses)))
+	for _, address := range addresses {
+		wallet := ws.Wallets[address]
+		privKey := make([]byte, privKeyLen)
+		wallet.PrivateKey.D.FillBytes(privKey)
+
+		e.WriteBytes(privKey)
+		e.WriteBytes(wallet.PublicKey)
+	}
+
+	watched := ws.GetWatchOnlyAddresses()
+	sort.Strings(watched)
+
+	e.WriteUvarint(uint64(len(watched)))
+	for _, address := range watched {
+		watchOnly := ws.WatchOnly[address]
+		addressVersion := version
+		if watchOnly.Schnorr {
+			addressVersion = schnorrVersion
+		}
+
+		e.WriteBytes(watchOnly.PubKeyHash)
+		e.WriteBytes(watchOnly.PublicKey)
+		e.WriteUvarint(uint64(addressVersion))
+	}
+
+	var labelled []string
+	for address := range ws.Labels {
+		labelled = append(labelled, address)
+	}
+	sort.Strings(labelled)
+
+	e.WriteUvarint(uint64(len(labelled)))
+	for _, address := range labelled {
+		e.WriteBytes([]byte(address))
+		e.WriteBytes([]byte(ws.Labels[address]))
+	}
+
+	return e.Bytes()
+}
+
+func decodeWallets(data []byte) (map[string]*Wallet, map[string]*WatchOnly, map[string]string, error) {
+	d := canonical.NewDecoder(data)
+
+	fileVersion := d.ReadUvarint()
+	if d.Err() == nil && fileVersion != walletFileVersion {
+		return nil, nil, nil, fmt.Errorf("unsupported wallet file version %d", fileVersion)
+	}
+
+	wallets, err := decodeKeys(d)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	watchOnly, err := decodeWatchOnly(d)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	labels, err := decodeLabels(d, wallets, watchOnly)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := d.Finish(); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to decode wallet file: %v", err)
+	}
+
+	return wallets, watchOnly, labels, nil
+}
+
+func decodeKeys(d *canonical.Decoder) (map[string]*Wallet, error) {
+	wallets := make(map[string]*Wallet)
+	count := d.ReadCount()
+	for i := 0; i < count && d.Err() == nil; i++ {
+		privKey := d.ReadBytes()
+		pubKey := d.ReadBytes()
+		if d.Err() != nil {
+			break
+		}
+
+		wallet, err := newWalletFromKey(privKey, pubKeyKind(pubKey))
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(wallet.PublicKey, pubKey) {
+			return nil, errors.New("wallet file is corrupt: public key does not match private key")
+		}
+		wallets[string(wallet.GetAddress())] = wallet
+	}
+
+	return wallets, nil
+}
+
+func decodeWatchOnly(d *canonical.Decoder) (map[string]*WatchOnly, error) {
+	watchOnly := make(map[string]*WatchOnly)
+	count := d.ReadCount()
+	for i := 0; i < count && d.Err() == nil; i++ {
+		pubKeyHash := d.ReadBytes()
+		pubKey := d.ReadBytes()
+		addressVersion := d.ReadUvarint()
+		if d.Err() != nil {
+			break
+		}
+		if addressVersion != uint64(version) && addressVersion != uint64(schnorrVersion) {
+			return nil, fmt.Errorf("wallet file is corrupt: unknown address version %d", addressVersion)
+		}
+
+		watched := &WatchOnly{PubKeyHash: pubKeyHash, Schnorr: addressVersion == uint64(schnorrVersion)}
+		if len(pubKey) > 0 {
+			if !bytes.Equal(HashPubKey(pubKey), pubKeyHash) || (pubKeyKind(pubKey) == schnorrKey) != watched.Schnorr {
+				return nil, errors.New("wallet file is corrupt: watch-only public key does not match its address")
+			}
+			watched.PublicKey = pubKey
+		}
+		watchOnly[watched.Address()] = watched
+	}
+
+	return watchOnly, nil
+}
+
+// Decode the labels, which must be of addresses in wallets or watchOnly
+func decodeLabels(d *canonical.Decoder, wallets map[string]*Wallet, watchOnly map[string]*WatchOnly) (map[string]string, error) {
+	labels := make(map[string]string)
+	count := d.ReadCount()
+	for i := 0; i < count && d.Err() == nil; i++ {
+		address := string(d.ReadBytes())
+		label := string(d.ReadBytes())
+		if d.Err() != nil {
+			break
+		}
+
+		if _, ok := wallets[address]; !ok && watchOnly[address] == nil {
+			return nil, fmt.Errorf("wallet file is corrupt: label for unknown address %s", address)
+		}
+		labels[address] = label
+	}
+
+	return labels, nil
+}
+
+// Layout of wallet files written with encoding/gob before the canonical
+// format.  The curve is always P-256 so it is not decoded.
+type legacyWallets struct {
+	Wallets map[string]*struct {
+		PrivateKey struct {
+			PublicKey struct {
+				X, Y *big.Int
+			}
+			D *big.Int
+		}
+		PublicKey []byte
+	}
+}
+
+func decodeLegacyWallets(data []byte) (map[string]*Wallet, error) {
+	var legacy legacyWallets
+	decoder := gob.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&legacy); err != nil {
+		return nil, fmt.Errorf("failed to decode legacy wallet file: %v", err)
+	}
+
+	wallets := make(map[string]*Wallet)
+	for address, w := range legacy.Wallets {
+		if w == nil || w.PrivateKey.D == nil {
+			return nil, fmt.Errorf("%w: legacy wallet %s has no private key", ErrInvalidPrivateKey, address)
+		}
+		wallet, err := newWalletFromKey(w.PrivateKey.D.Bytes(), legacyKey)
+		if err != nil {
+			return nil, err
+		}
+		wallets[address] = wallet
+	}
+
+	return wallets, nil
+}
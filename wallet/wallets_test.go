@@ -0,0 +1,54 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"testing"
+)
+
+func TestDecodeLegacyWallets(t *testing.T) {
+	data, err := ioutil.ReadFile("../wallet.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallets, err := decodeLegacyWallets(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wallets) == 0 {
+		t.Fatal("no wallets decoded")
+	}
+	for address, w := range wallets {
+		if w.PrivateKey.D == nil {
+			t.Fatalf("wallet of %s has no private key", address)
+		}
+	}
+}
+
+// A crafted legacy file whose key has no private scalar must be rejected
+// rather than crash the loader
+func TestDecodeLegacyWalletsWithoutPrivateKey(t *testing.T) {
+	var legacy legacyWallets
+	legacy.Wallets = map[string]*struct {
+		PrivateKey struct {
+			PublicKey struct {
+				X, Y *big.Int
+			}
+			D *big.Int
+		}
+		PublicKey []byte
+	}{"address": {PublicKey: []byte{1}}}
+	legacy.Wallets["address"].PrivateKey.PublicKey.X = big.NewInt(1)
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := decodeLegacyWallets(buf.Bytes()); !errors.Is(err, ErrInvalidPrivateKey) {
+		t.Fatalf("got %v, want %v", err, ErrInvalidPrivateKey)
+	}
+}
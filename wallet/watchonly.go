@@ -0,0 +1,94 @@
+package wallet
+
+import (
+	"fmt"
+
+	"github.com/jplesperance/tcn/encoding/base58"
+	"github.com/jplesperance/tcn/encoding/ecc"
+)
+
+// WatchOnly is an address tracked without its private key, so its balance
+// can be reported but its outputs cannot be spent
+//
+// PubKeyHash: the public key hash the address encodes
+// PublicKey: the public key, nil unless imported with its public key
+// Schnorr: whether the address is that of a Schnorr key
+type WatchOnly struct {
+	PubKeyHash []byte
+	PublicKey  []byte
+	Schnorr    bool
+}
+
+// Address returns the watch-only address
+func (w WatchOnly) Address() string {
+	if w.Schnorr {
+		return string(encodeAddress(schnorrVersion, w.PubKeyHash))
+	}
+
+	return string(encodeAddress(version, w.PubKeyHash))
+}
+
+// ImportAddress adds address to the wallets as watch-only, returning
+// whether it was not already present
+func (ws *Wallets) ImportAddress(address string) (bool, error) {
+	if !ValidateAddress(address) {
+		return false, fmt.Errorf("%w: %s", ErrInvalidAddress, address)
+	}
+	decoded := base58.Decode([]byte(address))
+	pubKeyHash := decoded[1 : len(decoded)-AddressChecksumLen]
+	if _, ok := ws.Wallets[address]; ok {
+		return false, nil
+	}
+	if _, ok := ws.WatchOnly[address]; ok {
+		return false, nil
+	}
+
+	ws.WatchOnly[address] = &WatchOnly{PubKeyHash: pubKeyHash, Schnorr: decoded[0] == schnorrVersion}
+	log.Info("imported watch-only address", "address", address)
+
+	return true, nil
+}
+
+// ImportPubKey adds the address of a public key to the wallets as
+// watch-only, returning the address and whether it was not already present.
+// A 32 byte key is a Schnorr key, such as one aggregating several keys.
+func (ws *Wallets) ImportPubKey(pubKey []byte) (string, bool, error) {
+	schnorr := pubKeyKind(pubKey) == schnorrKey
+	parse := ecc.ParsePubKey
+	if schnorr {
+		parse = ecc.ParseSchnorrPubKey
+	}
+	if _, err := parse(pubKey); err != nil {
+		return "", false, fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+	}
+
+	pubKeyHash := HashPubKey(pubKey)
+	address := string(PubKeyAddress(pubKey))
+	if _, ok := ws.Wallets[address]; ok {
+		return address, false, nil
+	}
+	if watched, ok := ws.WatchOnly[address]; ok {
+		watched.PublicKey = pubKey
+		return address, false, nil
+	}
+
+	ws.WatchOnly[address] = &WatchOnly{PubKeyHash: pubKeyHash, PublicKey: pubKey, Schnorr: schnorr}
+	log.Info("imported watch-only public key", "address", address)
+
+	return address, true, nil
+}
+
+// GetWatchOnlyAddresses lists the watch-only addresses
+func (ws *Wallets) GetWatchOnlyAddresses() []string {
+	var addresses []string
+	for address := range ws.WatchOnly {
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
+// IsWatchOnly reports whether address is tracked without its private key
+func (ws *Wallets) IsWatchOnly(address string) bool {
+	_, ok := ws.WatchOnly[address]
+	return ok
+}